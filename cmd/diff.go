@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gosqlpp/internal/database"
+	"gosqlpp/internal/diff"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffConnections []string
+	diffKeys        []string
+)
+
+// diffCmd compares the results of the same statements on two connections
+var diffCmd = &cobra.Command{
+	Use:   "diff -c left -c right file...",
+	Short: "Compare query results across two connections",
+	Long: `diff runs each statement of the SQL files on two connections and compares
+their result sets, to check that a replica, a migrated database or a
+refactored view returns the same data as the original.
+
+Rows are matched on the --key columns, or by position when no key is given
+or a result set lacks the key columns; a -- @diff-key hint sets the keys of
+a single statement. Rows are reported as removed (only on the left), added
+(only on the right) or changed. The report is a table by default; -o json
+writes a JSON document and -o unified a unified diff. The command exits with
+a non-zero status when differences are found.
+
+Examples:
+  sqlpp diff -c prod -c replica checks.sql
+  sqlpp diff -c old -c new --key id -o unified views.sql`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().StringArrayVarP(&diffConnections, "connection", "c", nil,
+		"connection to compare, given twice: first the left, then the right")
+	diffCmd.Flags().StringSliceVarP(&diffKeys, "key", "k", nil,
+		"columns that identify a row (default: compare rows by position)")
+	rootCmd.AddCommand(diffCmd)
+}
+
+// runDiff runs the files on both connections and writes the report
+func runDiff(cmd *cobra.Command, args []string) error {
+	if len(diffConnections) != 2 || diffConnections[0] == diffConnections[1] {
+		return fmt.Errorf("diff needs two different connections: -c left -c right")
+	}
+
+	style := outputFormat
+	if style == "" {
+		style = diff.StyleTable
+	}
+	valid := false
+	for _, name := range diff.Styles {
+		valid = valid || name == style
+	}
+	if !valid {
+		return fmt.Errorf("invalid output style '%s' for diff, must be one of: %s", style, strings.Join(diff.Styles, ", "))
+	}
+	if outputFile != "" || outputDir != "" {
+		return fmt.Errorf("diff writes its report to standard output; redirect it to save it to a file")
+	}
+
+	effectiveConfig := *cfg
+	effectiveConfig.Output = "table"
+	if cmd.Flags().Changed("max-rows") {
+		effectiveConfig.MaxRows = maxRows
+	}
+	applyValueFlags(cmd, &effectiveConfig)
+	if err := effectiveConfig.ValidateBasic(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	// Errors from here on come from execution, not from command line usage,
+	// and are printed once by main
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	dbManager := database.NewManager()
+	defer dbManager.CloseAll()
+
+	var executors []*database.Executor
+	for _, name := range diffConnections {
+		connConfig, err := effectiveConfig.GetConnection(name)
+		if err != nil {
+			return fmt.Errorf("connection error: %w", err)
+		}
+		if err := dbManager.Connect(name, connConfig); err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", name, err)
+		}
+		conn, err := dbManager.GetConnection(name)
+		if err != nil {
+			return fmt.Errorf("failed to get database connection: %w", err)
+		}
+		executor, err := newExecutor(conn, connConfig, effectiveConfig.MaxRows)
+		if err != nil {
+			return fmt.Errorf("configuration error: %w", err)
+		}
+		defer executor.Close()
+		executors = append(executors, executor)
+	}
+
+	differ := diff.NewDiffer(executors[0], executors[1], diffKeys)
+	report := &diff.Report{Left: diffConnections[0], Right: diffConnections[1]}
+	for _, filename := range args {
+		fmt.Fprintf(os.Stderr, "Processing file: %s\n", filename)
+		if err := differ.DiffFile(report, filename); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch style {
+	case diff.StyleJSON:
+		err = report.WriteJSON(os.Stdout)
+	case diff.StyleUnified:
+		err = report.WriteUnified(os.Stdout)
+	default:
+		formatter, formatterErr := newFormatter(&effectiveConfig)
+		if formatterErr != nil {
+			return formatterErr
+		}
+		err = report.WriteTable(formatter)
+		closeFormatter(formatter)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing diff report: %w", err)
+	}
+
+	if differences := report.Differences(); differences > 0 {
+		return fmt.Errorf("diff found %d difference(s)", differences)
+	}
+	return nil
+}
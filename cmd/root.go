@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gosqlpp/internal/config"
 	"gosqlpp/internal/database"
@@ -34,6 +35,38 @@ var (
 	useStdin        bool
 	listConnections bool
 	showVersion     bool
+	showTiming      bool
+	showSummary     bool
+	summaryJSON     bool
+	slowestCount    int
+	dryRun          bool
+	explainPlans    bool
+	explainRunDDL   bool
+	maxRows         int
+	yesIMeanIt      bool
+	htmlDocument    bool
+	htmlCSS         string
+	insertTable     string
+	insertDialect   string
+	insertBatch     int
+	templateFile    string
+	outputFile      string
+	outputDir       string
+	outputName      string
+	nullDisplay     string
+	floatFormat     string
+	floatPrecision  int
+	timeFormat      string
+	timeZone        string
+	boolFormat      string
+	delimiter       string
+	noHeader        bool
+	crlf            bool
+	quoteAll        bool
+	writeBOM        bool
+	maxColWidth     int
+	wrapColumns     bool
+	colorMode       string
 
 	// Global config
 	cfg *config.Config
@@ -47,10 +80,20 @@ var rootCmd = &cobra.Command{
 It provides #include, #define, and conditional preprocessing capabilities,
 along with schema introspection and multiple output formats.
 
+Script Commands:
+  @explain [on|off]                   # Show the query plan of the next statement (or all)
+  @spool <file>|off                   # Write later results to a file
+
 Schema Commands:
   @drivers                            # List all available database drivers
   @schema-tables [filter]             # List database tables
   @schema-views [filter]              # List database views  
+  @schema-columns [filter]            # List the columns of tables
+  @describe <table>                   # Describe the columns of a table or view
+  @schema-indexes [filter]            # List the indexes of tables
+  @schema-constraints [filter]        # List primary key, unique and foreign key constraints
+  @schema-foreignkeys [filter]        # List foreign keys and their rules
+  @schema-ddl [filter]                # Script tables, views and routines as SQL
   @schema-procedures [filter]         # List stored procedures
   @schema-functions [filter]          # List functions
   @schema-all [filter]                # Show all schema information
@@ -81,7 +124,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&connectionName, "connection", "c", "",
 		"database connection name from config")
 	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "",
-		"output format (table, json, yaml, csv)")
+		"output format (table, json, yaml, csv, tsv, vertical, auto, markdown, html, asciidoc, sql-insert, ndjson, json-envelope, template, xlsx)")
 	rootCmd.PersistentFlags().StringVarP(&inputFile, "file", "f", "",
 		"SQL file to process")
 	rootCmd.PersistentFlags().StringVarP(&inputDirectory, "directory", "d", "",
@@ -96,6 +139,70 @@ func init() {
 		"list available database connections and exit")
 	rootCmd.PersistentFlags().BoolVarP(&showVersion, "version", "v", false,
 		"show version information and exit")
+	rootCmd.PersistentFlags().BoolVar(&showTiming, "timing", false,
+		"print the elapsed time after each statement and a summary at the end")
+	rootCmd.PersistentFlags().BoolVar(&showSummary, "summary", false,
+		"print an execution summary at the end of the run")
+	rootCmd.PersistentFlags().BoolVar(&summaryJSON, "summary-json", false,
+		"print the execution summary as JSON")
+	rootCmd.PersistentFlags().IntVar(&slowestCount, "slowest", 5,
+		"number of slowest statements listed in the summary")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false,
+		"validate statements against the database without changing it")
+	rootCmd.PersistentFlags().BoolVar(&explainPlans, "explain", false,
+		"show query plans for SELECT and DML statements instead of executing them")
+	rootCmd.PersistentFlags().BoolVar(&explainRunDDL, "explain-run-ddl", false,
+		"with --explain, run DDL and other statements that have no query plan instead of skipping them")
+	rootCmd.PersistentFlags().IntVar(&maxRows, "max-rows", 0,
+		"stop fetching each result set after this many rows (0 for no limit)")
+	rootCmd.PersistentFlags().BoolVar(&yesIMeanIt, "yes-i-mean-it", false,
+		"run destructive statements on protected connections without confirmation")
+	rootCmd.PersistentFlags().BoolVar(&htmlDocument, "html-document", false,
+		"write html output as a complete HTML document instead of standalone tables")
+	rootCmd.PersistentFlags().StringVar(&htmlCSS, "html-css", "",
+		"stylesheet file embedded in the HTML document (implies --html-document)")
+	rootCmd.PersistentFlags().StringVar(&insertTable, "insert-table", "",
+		"target table for sql-insert output")
+	rootCmd.PersistentFlags().StringVar(&insertDialect, "insert-dialect", "",
+		"driver whose SQL dialect sql-insert output uses (default: the connection's driver)")
+	rootCmd.PersistentFlags().IntVar(&insertBatch, "insert-batch", 1,
+		"number of rows per INSERT statement in sql-insert output")
+	rootCmd.PersistentFlags().StringVar(&templateFile, "template", "",
+		"Go text/template file that renders each result in template output")
+	rootCmd.PersistentFlags().StringVar(&outputFile, "output-file", "",
+		"write results to this file instead of standard output (format from its extension unless -o is given)")
+	rootCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "",
+		"write the rows of each statement to a file of its own in this directory")
+	rootCmd.PersistentFlags().StringVar(&outputName, "output-name", output.DefaultOutputName,
+		"file name pattern for --output-dir ({file}, {line}, {n} and {ext} are replaced)")
+	rootCmd.PersistentFlags().StringVar(&nullDisplay, "null-display", "",
+		"text shown for NULL values (default NULL, empty in csv)")
+	rootCmd.PersistentFlags().StringVar(&floatFormat, "float-format", "",
+		"notation for floating-point values: g, f or e (default g)")
+	rootCmd.PersistentFlags().IntVar(&floatPrecision, "float-precision", 0,
+		"digits shown for floating-point values (default: as many as needed)")
+	rootCmd.PersistentFlags().StringVar(&timeFormat, "time-format", "",
+		"Go layout or rfc3339, rfc3339nano, datetime, date or time for time values (default rfc3339nano)")
+	rootCmd.PersistentFlags().StringVar(&timeZone, "time-zone", "",
+		"time zone time values are converted to, such as UTC, local or Europe/Paris")
+	rootCmd.PersistentFlags().StringVar(&boolFormat, "bool-format", "",
+		"text for true and false values separated by a slash, such as yes/no")
+	rootCmd.PersistentFlags().StringVar(&delimiter, "delimiter", "",
+		"field delimiter for csv and tsv output, such as | or \\t (default , for csv and tab for tsv)")
+	rootCmd.PersistentFlags().BoolVar(&noHeader, "no-header", false,
+		"leave out the line of column names in csv and tsv output")
+	rootCmd.PersistentFlags().BoolVar(&crlf, "crlf", false,
+		"end csv and tsv lines with CRLF instead of LF")
+	rootCmd.PersistentFlags().BoolVar(&quoteAll, "quote-all", false,
+		"quote every field in csv and tsv output")
+	rootCmd.PersistentFlags().BoolVar(&writeBOM, "bom", false,
+		"start csv and tsv output with a UTF-8 byte order mark for Excel")
+	rootCmd.PersistentFlags().IntVar(&maxColWidth, "max-col-width", 0,
+		"truncate table columns to this many characters (0 for no limit)")
+	rootCmd.PersistentFlags().BoolVar(&wrapColumns, "wrap", false,
+		"wrap values wider than their table column instead of truncating them")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", "auto",
+		"color table output and errors: auto, always or never (auto honours NO_COLOR)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -131,22 +238,32 @@ func runSqlpp(cmd *cobra.Command, args []string) error {
 		}
 
 		// Determine effective output format
-		effectiveOutputFormat := cfg.Output
+		listConfig := *cfg
 		if outputFormat != "" {
-			effectiveOutputFormat = outputFormat
+			listConfig.Output = outputFormat
+		} else if format := output.FormatForFile(outputFile); format != "" {
+			listConfig.Output = format
+		}
+		applyValueFlags(cmd, &listConfig)
+		if err := listConfig.ValidateBasic(); err != nil {
+			return fmt.Errorf("configuration error: %w", err)
 		}
 
 		// Create output formatter
-		formatter := output.NewFormatter(effectiveOutputFormat, os.Stdout)
+		formatter, err := newFormatter(&listConfig)
+		if err != nil {
+			return err
+		}
+		defer closeFormatter(formatter)
 
 		// Format connection information as a table-like structure
-		var data []map[string]interface{}
+		var data []output.Record
 		for _, info := range connectionInfos {
-			row := map[string]interface{}{
-				"name":       info.Name,
-				"driver":     info.Driver,
-				"notes":      info.Notes,
-				"is_default": info.IsDefault,
+			row := output.Record{
+				{Key: "name", Value: info.Name},
+				{Key: "driver", Value: info.Driver},
+				{Key: "notes", Value: info.Notes},
+				{Key: "is_default", Value: info.IsDefault},
 			}
 			data = append(data, row)
 		}
@@ -211,18 +328,33 @@ func runSqlpp(cmd *cobra.Command, args []string) error {
 	}
 	if outputFormat != "" {
 		effectiveConfig.Output = outputFormat
+	} else if format := output.FormatForFile(outputFile); format != "" {
+		effectiveConfig.Output = format
 	}
 	if forceExecution {
 		effectiveConfig.EndOnError = false
 	}
+	if cmd.Flags().Changed("max-rows") {
+		effectiveConfig.MaxRows = maxRows
+	}
+	applyValueFlags(cmd, &effectiveConfig)
 
 	// Validate basic configuration (not connections yet)
 	if err := effectiveConfig.ValidateBasic(); err != nil {
 		return fmt.Errorf("configuration error: %w", err)
 	}
 
+	// Errors from here on come from execution, not from command line usage,
+	// and are printed once by main
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
 	// Create output formatter early (needed for connectionless commands)
-	formatter := output.NewFormatter(effectiveConfig.Output, os.Stdout)
+	formatter, err := newFormatter(&effectiveConfig)
+	if err != nil {
+		return err
+	}
+	defer closeFormatter(formatter)
 
 	// For stdin input, we need to check if connections are required
 	if isStdinInput {
@@ -256,27 +388,235 @@ func runSqlpp(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create executor and formatter
-	executor := database.NewExecutor(conn)
-	formatter = output.NewFormatter(effectiveConfig.Output, os.Stdout)
+	executor, err := newExecutor(conn, connConfig, effectiveConfig.MaxRows)
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	defer executor.Close()
+	if err := configureInsertOutput(formatter, connConfig.Driver); err != nil {
+		return err
+	}
 	introspector := schema.NewIntrospector(conn, formatter)
 
 	// Create file processor
 	processor := file.NewProcessor(executor, formatter, introspector, effectiveConfig.EndOnError)
+	configureProcessor(processor)
 
 	// Process files
+	var processErr error
 	if inputDirectory != "" {
-		fmt.Printf("Processing directory: %s\n", inputDirectory)
+		fmt.Fprintf(os.Stderr, "Processing directory: %s\n", inputDirectory)
 		if !newerThanTime.IsZero() {
-			fmt.Printf("Files newer than: %s\n", newerThanTime.Format("2006-01-02 15:04:05"))
+			fmt.Fprintf(os.Stderr, "Files newer than: %s\n", newerThanTime.Format("2006-01-02 15:04:05"))
 		}
-		return processor.ProcessDirectory(inputDirectory, newerThanTime)
+		processErr = processor.ProcessDirectory(inputDirectory, newerThanTime)
 	} else if isStdinInput {
-		fmt.Printf("Processing input from stdin\n")
-		return processor.ProcessStdin()
+		fmt.Fprintf(os.Stderr, "Processing input from stdin\n")
+		processErr = processor.ProcessStdin()
 	} else {
-		fmt.Printf("Processing file: %s\n", inputSource)
-		return processor.ProcessFile(inputSource)
+		fmt.Fprintf(os.Stderr, "Processing file: %s\n", inputSource)
+		processErr = processor.ProcessFile(inputSource)
 	}
+	return checkDryRun(processor, processErr)
+}
+
+// applyValueFlags overrides the value display settings of a configuration
+// with the flags given on the command line
+func applyValueFlags(cmd *cobra.Command, c *config.Config) {
+	if cmd.Flags().Changed("null-display") {
+		c.NullDisplay = &nullDisplay
+	}
+	if floatFormat != "" {
+		c.FloatFormat = floatFormat
+	}
+	if cmd.Flags().Changed("float-precision") {
+		c.FloatPrecision = &floatPrecision
+	}
+	if timeFormat != "" {
+		c.TimeFormat = timeFormat
+	}
+	if timeZone != "" {
+		c.TimeZone = timeZone
+	}
+	if boolFormat != "" {
+		c.BoolFormat = boolFormat
+	}
+}
+
+// newFormatter creates an output formatter writing to stdout or to the
+// output file, configured from the validated configuration and the command
+// line options
+func newFormatter(c *config.Config) (*output.Formatter, error) {
+	format := c.Output
+	if format == "xlsx" && outputFile == "" && outputDir == "" {
+		return nil, fmt.Errorf("xlsx output is a binary workbook: use --output-file or --output-dir")
+	}
+	formatter := output.NewFormatter(format, os.Stdout)
+	location, err := c.TimeLocation()
+	if err != nil {
+		return nil, err
+	}
+	formatter.SetValueOptions(output.ValueOptions{
+		NullDisplay:    c.NullDisplay,
+		FloatFormat:    c.FloatFormat,
+		FloatPrecision: c.FloatPrecision,
+		TimeFormat:     c.TimeFormat,
+		TimeZone:       location,
+		BoolFormat:     c.BoolFormat,
+	})
+	if htmlDocument || htmlCSS != "" {
+		var css []byte
+		if htmlCSS != "" {
+			var err error
+			if css, err = os.ReadFile(htmlCSS); err != nil {
+				return nil, fmt.Errorf("failed to read stylesheet: %w", err)
+			}
+		}
+		formatter.SetHTMLDocument(true, string(css))
+	}
+	if templateFile != "" {
+		if err := formatter.SetTemplate(templateFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := configureInsertOutput(formatter, ""); err != nil {
+		return nil, err
+	}
+	if err := configureDelimitedOutput(formatter); err != nil {
+		return nil, err
+	}
+	if maxColWidth < 0 {
+		return nil, fmt.Errorf("invalid --max-col-width %d, must be 0 (no limit) or more", maxColWidth)
+	}
+	formatter.SetMaxColumnWidth(maxColWidth, wrapColumns)
+	switch colorMode {
+	case "auto", "always", "never":
+		formatter.SetColor(colorMode)
+	default:
+		return nil, fmt.Errorf("invalid --color '%s', must be one of: auto, always, never", colorMode)
+	}
+	if outputDir != "" {
+		if err := formatter.SetOutputDir(outputDir, outputName); err != nil {
+			return nil, err
+		}
+	}
+	if outputFile != "" {
+		if err := formatter.SetOutputFile(outputFile); err != nil {
+			return nil, err
+		}
+	}
+	return formatter, nil
+}
+
+// closeFormatter finishes output that spans the whole run, such as an HTML
+// document or a JSON envelope
+func closeFormatter(formatter *output.Formatter) {
+	if err := formatter.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+	}
+}
+
+// configureInsertOutput sets up sql-insert output, using the dialect of the
+// connection's driver unless --insert-dialect names another one
+func configureInsertOutput(formatter *output.Formatter, driver string) error {
+	dialect := driver
+	if insertDialect != "" {
+		dialect = insertDialect
+		supported := false
+		for _, name := range database.GetSupportedDrivers() {
+			supported = supported || name == dialect
+		}
+		if !supported {
+			return fmt.Errorf("invalid --insert-dialect '%s', must be one of: %s",
+				dialect, strings.Join(database.GetSupportedDrivers(), ", "))
+		}
+	}
+	formatter.SetInsertOptions(insertTable, dialect, insertBatch)
+	return nil
+}
+
+// configureDelimitedOutput sets up csv and tsv output from the command line
+// options. The delimiter is a single character, or \t or tab for a tab.
+func configureDelimitedOutput(formatter *output.Formatter) error {
+	options := output.DelimitedOptions{
+		NoHeader: noHeader,
+		CRLF:     crlf,
+		QuoteAll: quoteAll,
+		BOM:      writeBOM,
+	}
+	switch delimiter {
+	case "":
+	case `\t`, "tab":
+		options.Delimiter = '\t'
+	default:
+		runes := []rune(delimiter)
+		if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' || runes[0] == utf8.RuneError {
+			return fmt.Errorf("invalid --delimiter '%s', must be a single character other than a quote or line break", delimiter)
+		}
+		options.Delimiter = runes[0]
+	}
+	formatter.SetDelimitedOptions(options)
+	return nil
+}
+
+// newExecutor creates an executor configured from the command line options,
+// the row limit and the connection's retry settings
+func newExecutor(conn *database.Connection, connConfig config.Connection, maxRows int) (*database.Executor, error) {
+	executor := database.NewExecutor(conn)
+	executor.SetDryRun(dryRun)
+	executor.SetMaxRows(int64(maxRows))
+	executor.SetProtected(connConfig.Protected)
+	executor.AllowDestructive(yesIMeanIt)
+	if connConfig.Retry != nil {
+		policy, err := database.NewRetryPolicy(connConfig.Retry)
+		if err != nil {
+			return nil, err
+		}
+		executor.SetRetryPolicy(policy)
+	}
+	return executor, nil
+}
+
+// checkDryRun turns invalid statements found by a dry run into an error so
+// the command exits with a non-zero status
+func checkDryRun(processor *file.Processor, processErr error) error {
+	if processErr != nil || !dryRun || processor.Summary() == nil {
+		return processErr
+	}
+	if failed := processor.Summary().Failed; failed > 0 {
+		return fmt.Errorf("dry run found %d invalid statement(s)", failed)
+	}
+	return nil
+}
+
+// configureProcessor applies the command line reporting options to a processor
+func configureProcessor(processor *file.Processor) {
+	processor.SetTiming(showTiming)
+	processor.SetExplain(explainPlans)
+	processor.SetExplainRunOthers(explainRunDDL)
+
+	summaryMode := file.SummaryNone
+	if summaryJSON {
+		summaryMode = file.SummaryJSON
+	} else if showSummary || showTiming {
+		summaryMode = file.SummaryText
+	}
+	processor.SetSummary(summaryMode, slowestCount)
+	processor.SetConfirm(confirmOnTerminal)
+}
+
+// confirmOnTerminal asks a yes/no question when stdin is a terminal. Without
+// a terminal, such as in CI or when SQL is piped in, the answer is no.
+func confirmOnTerminal(prompt string) bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
 
 // GetConfig returns the loaded configuration (for use by other packages)
@@ -330,30 +670,38 @@ func handleStdinWithOptionalConnection(cfg *config.Config, formatter *output.For
 		}
 
 		// Create executor and introspector with database connection
-		executor := database.NewExecutor(conn)
+		executor, err := newExecutor(conn, connConfig, cfg.MaxRows)
+		if err != nil {
+			return fmt.Errorf("configuration error: %w", err)
+		}
+		defer executor.Close()
+		if err := configureInsertOutput(formatter, connConfig.Driver); err != nil {
+			return err
+		}
 		introspector := schema.NewIntrospector(conn, formatter)
 
 		// Create file processor
 		processor := file.NewProcessor(executor, formatter, introspector, cfg.EndOnError)
+		configureProcessor(processor)
 
 		// Process the input
-		fmt.Printf("Processing input from stdin\n")
-		return processor.ProcessStdinText(inputText)
+		fmt.Fprintf(os.Stderr, "Processing input from stdin\n")
+		return checkDryRun(processor, processor.ProcessStdinText(inputText))
 	} else {
 		// No database connection needed, create introspector without connection
 		introspector := schema.NewIntrospector(nil, formatter)
 
 		// Handle connectionless commands directly
-		fmt.Printf("Processing input from stdin\n")
-		return processConnectionlessInput(inputText, introspector)
+		fmt.Fprintf(os.Stderr, "Processing input from stdin\n")
+		return processConnectionlessInput(inputText, introspector, formatter)
 	}
 }
 
 // processConnectionlessInput processes input that doesn't require database connections
-func processConnectionlessInput(inputText string, introspector *schema.Introspector) error {
+func processConnectionlessInput(inputText string, introspector *schema.Introspector, formatter *output.Formatter) error {
 	lines := strings.Split(inputText, "\n")
 
-	for _, line := range lines {
+	for i, line := range lines {
 		trimmedLine := strings.TrimSpace(line)
 
 		// Skip empty lines and comments
@@ -373,6 +721,7 @@ func processConnectionlessInput(inputText string, introspector *schema.Introspec
 				filter = strings.Trim(filter, "\"")
 			}
 
+			formatter.SetCommandLocation(output.CommandLocation{File: "<stdin>", Line: i + 1, SQL: trimmedLine})
 			if err := introspector.ProcessSchemaCommand(command, filter); err != nil {
 				return fmt.Errorf("error processing command %s: %w", command, err)
 			}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,17 +15,95 @@ const ConfigFileName = ".sqlppconfig"
 
 // Connection represents a database connection configuration
 type Connection struct {
-	Driver           string `yaml:"driver"`
-	Notes            string `yaml:"notes,omitempty"`
-	ConnectionString string `yaml:"connection-string"`
+	Driver           string       `yaml:"driver"`
+	Notes            string       `yaml:"notes,omitempty"`
+	ConnectionString string       `yaml:"connection-string"`
+	Retry            *RetryConfig `yaml:"retry,omitempty"`
+	// Protected connections refuse destructive statements unless confirmed
+	Protected bool `yaml:"protected,omitempty"`
+	// ReadOnly connections only run queries, in read-only sessions
+	ReadOnly bool `yaml:"read-only,omitempty"`
+}
+
+// RetryConfig controls automatic retries of statements that fail with
+// transient errors such as deadlocks or connection resets
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int `yaml:"max-attempts"`
+	// Backoff is the delay before the first retry, doubled for each further retry
+	Backoff string `yaml:"backoff,omitempty"`
+	// MaxBackoff caps the delay between retries
+	MaxBackoff string `yaml:"max-backoff,omitempty"`
+	// TransientCodes replaces the driver's default list of transient error codes
+	TransientCodes []string `yaml:"transient-codes,omitempty"`
+}
+
+// Default retry settings used when a retry block leaves them unset
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultRetryMaxBackoff = 5 * time.Second
+)
+
+// Validate checks the retry settings
+func (r *RetryConfig) Validate() error {
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("max-attempts must not be negative")
+	}
+	if _, err := r.BackoffDuration(); err != nil {
+		return err
+	}
+	if _, err := r.MaxBackoffDuration(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Attempts returns the configured number of attempts, or the default
+func (r *RetryConfig) Attempts() int {
+	if r.MaxAttempts == 0 {
+		return DefaultRetryAttempts
+	}
+	return r.MaxAttempts
+}
+
+// BackoffDuration returns the initial retry delay, or the default
+func (r *RetryConfig) BackoffDuration() (time.Duration, error) {
+	return parseRetryDuration("backoff", r.Backoff, DefaultRetryBackoff)
+}
+
+// MaxBackoffDuration returns the maximum retry delay, or the default
+func (r *RetryConfig) MaxBackoffDuration() (time.Duration, error) {
+	return parseRetryDuration("max-backoff", r.MaxBackoff, DefaultRetryMaxBackoff)
+}
+
+// parseRetryDuration parses a duration setting such as "250ms" or "2s"
+func parseRetryDuration(name, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s '%s': use a duration such as 250ms or 2s", name, value)
+	}
+	return d, nil
 }
 
 // Config represents the application configuration
 type Config struct {
-	DefaultConnection string                `yaml:"default-connection"`
-	EndOnError        bool                  `yaml:"end-on-error"`
-	Output            string                `yaml:"output"`
-	Connections       map[string]Connection `yaml:"connections"`
+	DefaultConnection string `yaml:"default-connection"`
+	EndOnError        bool   `yaml:"end-on-error"`
+	Output            string `yaml:"output"`
+	MaxRows           int    `yaml:"max-rows,omitempty"`
+	// NullDisplay, FloatFormat, FloatPrecision, TimeFormat, TimeZone and
+	// BoolFormat control how values are rendered by the text output formats
+	NullDisplay    *string               `yaml:"null-display,omitempty"`
+	FloatFormat    string                `yaml:"float-format,omitempty"`
+	FloatPrecision *int                  `yaml:"float-precision,omitempty"`
+	TimeFormat     string                `yaml:"time-format,omitempty"`
+	TimeZone       string                `yaml:"time-zone,omitempty"`
+	BoolFormat     string                `yaml:"bool-format,omitempty"`
+	Connections    map[string]Connection `yaml:"connections"`
 }
 
 // DefaultConfig returns a configuration with sensible defaults
@@ -147,23 +226,83 @@ func (c *Config) Validate() error {
 	return c.ValidateConnections()
 }
 
+// OutputFormats lists the output formats accepted in the configuration and
+// on the command line
+var OutputFormats = []string{"table", "json", "yaml", "csv", "tsv", "vertical", "auto", "markdown", "html", "asciidoc", "sql-insert", "ndjson", "json-envelope", "template", "xlsx"}
+
+// IsOutputFormat checks whether format is one of OutputFormats
+func IsOutputFormat(format string) bool {
+	for _, valid := range OutputFormats {
+		if format == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOutputFormat returns an error naming the supported formats when
+// format is not one of OutputFormats
+func ValidateOutputFormat(format string) error {
+	if !IsOutputFormat(format) {
+		return fmt.Errorf("invalid output format '%s', must be one of: %s", format, strings.Join(OutputFormats, ", "))
+	}
+	return nil
+}
+
 // ValidateBasic validates basic configuration that doesn't require database connections
 func (c *Config) ValidateBasic() error {
 	// Validate output format
-	validOutputs := map[string]bool{
-		"table": true,
-		"json":  true,
-		"yaml":  true,
-		"csv":   true,
+	if err := ValidateOutputFormat(c.Output); err != nil {
+		return err
+	}
+
+	if c.MaxRows < 0 {
+		return fmt.Errorf("invalid max-rows %d, must be 0 (no limit) or more", c.MaxRows)
+	}
+
+	return c.validateValueDisplay()
+}
+
+// validateValueDisplay checks the settings that control how values are rendered
+func (c *Config) validateValueDisplay() error {
+	switch c.FloatFormat {
+	case "", "g", "f", "e":
+	default:
+		return fmt.Errorf("invalid float-format '%s', must be one of: g, f, e", c.FloatFormat)
+	}
+
+	if c.FloatPrecision != nil && *c.FloatPrecision < 0 {
+		return fmt.Errorf("invalid float-precision %d, must be 0 or more", *c.FloatPrecision)
 	}
 
-	if !validOutputs[c.Output] {
-		return fmt.Errorf("invalid output format '%s', must be one of: table, json, yaml, csv", c.Output)
+	if _, err := c.TimeLocation(); err != nil {
+		return err
+	}
+
+	if c.BoolFormat != "" && strings.Count(c.BoolFormat, "/") != 1 {
+		return fmt.Errorf("invalid bool-format '%s', use true and false text separated by a slash, such as yes/no", c.BoolFormat)
 	}
 
 	return nil
 }
 
+// TimeLocation returns the time zone that times are converted to before
+// they are displayed, or nil to keep them as the database returned them.
+// The time-zone setting is an IANA name such as Europe/Paris, UTC or local.
+func (c *Config) TimeLocation() (*time.Location, error) {
+	switch strings.ToLower(c.TimeZone) {
+	case "":
+		return nil, nil
+	case "local":
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time-zone '%s': %w", c.TimeZone, err)
+	}
+	return loc, nil
+}
+
 // ValidateConnections validates database connection configuration
 func (c *Config) ValidateConnections() error {
 	// Validate connections
@@ -178,6 +317,11 @@ func (c *Config) ValidateConnections() error {
 		if conn.ConnectionString == "" {
 			return fmt.Errorf("connection '%s' missing connection-string", name)
 		}
+		if conn.Retry != nil {
+			if err := conn.Retry.Validate(); err != nil {
+				return fmt.Errorf("connection '%s' has invalid retry settings: %w", name, err)
+			}
+		}
 	}
 
 	// Validate default connection
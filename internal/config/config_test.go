@@ -3,6 +3,7 @@ package config
 import (
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -157,6 +158,106 @@ func TestConfigValidation(t *testing.T) {
 			expectError: true,
 			errorMsg:    "no default-connection specified",
 		},
+		{
+			name: "valid retry settings",
+			config: &Config{
+				DefaultConnection: "test",
+				Output:            "table",
+				Connections: map[string]Connection{
+					"test": {
+						Driver:           "postgres",
+						ConnectionString: "postgres://localhost/test",
+						Retry: &RetryConfig{
+							MaxAttempts:    5,
+							Backoff:        "250ms",
+							MaxBackoff:     "2s",
+							TransientCodes: []string{"40001", "40P01"},
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid retry backoff",
+			config: &Config{
+				DefaultConnection: "test",
+				Output:            "table",
+				Connections: map[string]Connection{
+					"test": {
+						Driver:           "sqlite3",
+						ConnectionString: "test.db",
+						Retry:            &RetryConfig{Backoff: "soon"},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid backoff",
+		},
+		{
+			name: "negative max-rows",
+			config: &Config{
+				DefaultConnection: "test",
+				Output:            "table",
+				MaxRows:           -1,
+				Connections: map[string]Connection{
+					"test": {
+						Driver:           "sqlite3",
+						ConnectionString: "test.db",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid max-rows",
+		},
+		{
+			name: "invalid float-format",
+			config: &Config{
+				DefaultConnection: "test",
+				Output:            "table",
+				FloatFormat:       "x",
+				Connections: map[string]Connection{
+					"test": {
+						Driver:           "sqlite3",
+						ConnectionString: "test.db",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid float-format",
+		},
+		{
+			name: "invalid time-zone",
+			config: &Config{
+				DefaultConnection: "test",
+				Output:            "table",
+				TimeZone:          "Nowhere/Special",
+				Connections: map[string]Connection{
+					"test": {
+						Driver:           "sqlite3",
+						ConnectionString: "test.db",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid time-zone",
+		},
+		{
+			name: "invalid bool-format",
+			config: &Config{
+				DefaultConnection: "test",
+				Output:            "table",
+				BoolFormat:        "yes",
+				Connections: map[string]Connection{
+					"test": {
+						Driver:           "sqlite3",
+						ConnectionString: "test.db",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid bool-format",
+		},
 	}
 
 	for _, tt := range tests {
@@ -320,3 +421,41 @@ func setTestConfigDir(dir string) func() {
 		configDirFunc = original
 	}
 }
+
+func TestRetryConfigDefaults(t *testing.T) {
+	retry := &RetryConfig{}
+
+	if retry.Attempts() != DefaultRetryAttempts {
+		t.Errorf("Expected %d attempts, got %d", DefaultRetryAttempts, retry.Attempts())
+	}
+	if backoff, err := retry.BackoffDuration(); err != nil || backoff != DefaultRetryBackoff {
+		t.Errorf("Expected backoff %s, got %s (%v)", DefaultRetryBackoff, backoff, err)
+	}
+	if maxBackoff, err := retry.MaxBackoffDuration(); err != nil || maxBackoff != DefaultRetryMaxBackoff {
+		t.Errorf("Expected max backoff %s, got %s (%v)", DefaultRetryMaxBackoff, maxBackoff, err)
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	tests := []struct {
+		zone     string
+		expected *time.Location
+	}{
+		{"", nil},
+		{"local", time.Local},
+		{"UTC", time.UTC},
+	}
+	for _, tt := range tests {
+		loc, err := (&Config{TimeZone: tt.zone}).TimeLocation()
+		if err != nil {
+			t.Fatalf("TimeLocation(%q) returned error: %v", tt.zone, err)
+		}
+		if loc != tt.expected {
+			t.Errorf("TimeLocation(%q) = %v, expected %v", tt.zone, loc, tt.expected)
+		}
+	}
+
+	if _, err := (&Config{TimeZone: "Nowhere/Special"}).TimeLocation(); err == nil {
+		t.Error("Expected an error for an unknown time zone")
+	}
+}
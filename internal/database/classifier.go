@@ -0,0 +1,237 @@
+package database
+
+// StatementKind describes how a statement should be executed
+type StatementKind int
+
+const (
+	// StatementExec is a statement that does not return rows
+	StatementExec StatementKind = iota
+	// StatementQuery is a statement that returns rows
+	StatementQuery
+	// StatementAmbiguous may or may not return rows, such as a procedure call
+	// or a batch mixing queries and other statements
+	StatementAmbiguous
+)
+
+// String returns a readable name for the statement kind
+func (k StatementKind) String() string {
+	switch k {
+	case StatementQuery:
+		return "query"
+	case StatementAmbiguous:
+		return "ambiguous"
+	default:
+		return "exec"
+	}
+}
+
+// queryKeywords start statements that always return rows
+var queryKeywords = map[string]bool{
+	"SELECT":   true,
+	"VALUES":   true,
+	"TABLE":    true, // PostgreSQL/MySQL 8 shorthand for SELECT * FROM
+	"SHOW":     true, // MySQL/PostgreSQL specific
+	"DESCRIBE": true, // MySQL specific
+	"DESC":     true, // MySQL specific
+	"EXPLAIN":  true, // Query execution plans
+}
+
+// dmlKeywords start statements that return rows only with a RETURNING or OUTPUT clause
+var dmlKeywords = map[string]bool{
+	"INSERT":  true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"MERGE":   true,
+	"REPLACE": true,
+	"UPSERT":  true,
+}
+
+// callKeywords start procedure calls, which may or may not return rows
+var callKeywords = map[string]bool{
+	"CALL":    true,
+	"EXEC":    true,
+	"EXECUTE": true,
+}
+
+// ClassifyStatement decides whether a statement returns rows. Comments are
+// ignored, and batches of several statements are classified as a whole.
+func ClassifyStatement(statement string) StatementKind {
+	return classifyStatement(statement, "")
+}
+
+// classifyStatement classifies a statement in a driver's dialect
+func classifyStatement(statement, driver string) StatementKind {
+	parts := statementParts(statement, driver)
+	if len(parts) == 0 {
+		return StatementExec
+	}
+
+	kind := classifyTokens(parts[0])
+	for _, part := range parts[1:] {
+		if classifyTokens(part) != kind {
+			return StatementAmbiguous
+		}
+	}
+	return kind
+}
+
+// classifyTokens classifies a single statement without comments
+func classifyTokens(tokens []Token) StatementKind {
+	// Skip leading parentheses, as in (SELECT ...) UNION (SELECT ...)
+	for len(tokens) > 0 && tokens[0].IsPunct("(") {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return StatementExec
+	}
+
+	keyword := tokens[0].Keyword()
+	switch {
+	case queryKeywords[keyword]:
+		return StatementQuery
+	case keyword == "WITH":
+		return classifyWith(tokens[1:])
+	case dmlKeywords[keyword]:
+		if hasTopLevelKeyword(tokens, "RETURNING", "OUTPUT") {
+			return StatementQuery
+		}
+		return StatementExec
+	case keyword == "PRAGMA":
+		// PRAGMA name = value sets a value; other forms report values as rows
+		if hasTopLevelPunct(tokens, "=") {
+			return StatementAmbiguous
+		}
+		return StatementQuery
+	case callKeywords[keyword]:
+		return StatementAmbiguous
+	default:
+		return StatementExec
+	}
+}
+
+// classifyWith classifies a statement after its WITH clause by finding the
+// first top-level SELECT or DML keyword following the common table expressions
+func classifyWith(tokens []Token) StatementKind {
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.IsPunct("("):
+			depth++
+		case tok.IsPunct(")"):
+			depth--
+		case depth == 0:
+			keyword := tok.Keyword()
+			if keyword == "SELECT" || keyword == "VALUES" || dmlKeywords[keyword] {
+				return classifyTokens(tokens[i:])
+			}
+		}
+	}
+	return StatementQuery
+}
+
+// hasTopLevelKeyword reports whether any of the keywords appear outside parentheses
+func hasTopLevelKeyword(tokens []Token, keywords ...string) bool {
+	depth := 0
+	for _, tok := range tokens {
+		switch {
+		case tok.IsPunct("("):
+			depth++
+		case tok.IsPunct(")"):
+			depth--
+		case depth == 0:
+			for _, keyword := range keywords {
+				if tok.Keyword() == keyword {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasTopLevelPunct reports whether the punctuation appears outside parentheses
+func hasTopLevelPunct(tokens []Token, punct string) bool {
+	depth := 0
+	for _, tok := range tokens {
+		switch {
+		case tok.IsPunct("("):
+			depth++
+		case tok.IsPunct(")"):
+			depth--
+		case depth == 0 && tok.IsPunct(punct):
+			return true
+		}
+	}
+	return false
+}
+
+// transactionKeywords start statements that control transactions
+var transactionKeywords = map[string]bool{
+	"BEGIN":     true,
+	"START":     true,
+	"COMMIT":    true,
+	"ROLLBACK":  true,
+	"END":       true,
+	"SAVEPOINT": true,
+	"RELEASE":   true,
+	"SAVE":      true,
+}
+
+// HasTransactionControl reports whether a statement, or any statement of a
+// batch, begins, ends or otherwise controls a transaction, such as BEGIN,
+// COMMIT, ROLLBACK or SAVEPOINT. Block keywords such as BEGIN TRY are not
+// transaction control, and statements in a trigger or procedure body are not
+// checked since they only run when the body does.
+func HasTransactionControl(statement string) bool {
+	return hasTransactionControl(statement, "")
+}
+
+// hasTransactionControl looks for transaction control in a driver's dialect
+func hasTransactionControl(statement, driver string) bool {
+	for _, part := range executedStatements(statement, driver) {
+		if isTransactionControl(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// beginTransactionKeywords are the words that make a BEGIN start a
+// transaction rather than a block
+var beginTransactionKeywords = map[string]bool{
+	"TRANSACTION": true,
+	"TRAN":        true,
+	"WORK":        true,
+	"DEFERRED":    true,
+	"IMMEDIATE":   true,
+	"EXCLUSIVE":   true,
+	"DISTRIBUTED": true,
+	"ISOLATION":   true,
+	"READ":        true,
+}
+
+// isTransactionControl checks a single statement without comments
+func isTransactionControl(tokens []Token) bool {
+	if len(tokens) == 0 || !transactionKeywords[tokens[0].Keyword()] {
+		return false
+	}
+
+	next := ""
+	if len(tokens) > 1 {
+		next = tokens[1].Keyword()
+	}
+
+	switch tokens[0].Keyword() {
+	case "BEGIN":
+		// BEGIN alone, BEGIN TRANSACTION/TRAN/WORK or SQLite's BEGIN IMMEDIATE and friends
+		return len(tokens) == 1 || beginTransactionKeywords[next]
+	case "START":
+		return next == "TRANSACTION"
+	case "END":
+		return len(tokens) == 1 || next == "TRANSACTION" || next == "WORK"
+	case "SAVE":
+		return next == "TRANSACTION" || next == "TRAN"
+	default:
+		return true
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import "testing"
+
+func TestClassifyStatement(t *testing.T) {
+	tests := []struct {
+		statement string
+		expected  StatementKind
+	}{
+		{"SELECT * FROM users", StatementQuery},
+		{"select 1", StatementQuery},
+		{"(SELECT 1) UNION (SELECT 2)", StatementQuery},
+		{"VALUES (1, 'a'), (2, 'b')", StatementQuery},
+		{"-- leading comment\nSELECT 1", StatementQuery},
+		{"/* block\ncomment */ SELECT 1", StatementQuery},
+		{"WITH t AS (SELECT 1 AS x) SELECT * FROM t", StatementQuery},
+		{"WITH t AS (SELECT 1 AS x) DELETE FROM users WHERE id IN (SELECT x FROM t)", StatementExec},
+		{"INSERT INTO users (name) VALUES ('a') RETURNING id", StatementQuery},
+		{"INSERT INTO users (name) OUTPUT inserted.id VALUES ('a')", StatementQuery},
+		{"INSERT INTO users (name) VALUES ('RETURNING')", StatementExec},
+		{"INSERT INTO users (name) SELECT name FROM old_users", StatementExec},
+		{"UPDATE users SET name = 'x'", StatementExec},
+		{"DELETE FROM users -- RETURNING id", StatementExec},
+		{"PRAGMA table_info(users)", StatementQuery},
+		{"PRAGMA journal_mode = WAL", StatementAmbiguous},
+		{"CALL refresh_stats()", StatementAmbiguous},
+		{"EXEC dbo.GetUsers", StatementAmbiguous},
+		{"CREATE TABLE users (id INTEGER)", StatementExec},
+		{"SHOW TABLES", StatementQuery},
+		{"EXPLAIN SELECT 1", StatementQuery},
+		{"INSERT INTO t VALUES (1); SELECT * FROM t", StatementAmbiguous},
+		{"SELECT 1; SELECT 2", StatementQuery},
+		{"CREATE TRIGGER trg AFTER INSERT ON t BEGIN UPDATE t SET x = 1; END", StatementExec},
+		{"CREATE PROCEDURE p() BEGIN SELECT 1; END", StatementExec},
+		{"BEGIN UPDATE t SET x = 1; SELECT x FROM t; END", StatementExec},
+		{"-- only a comment", StatementExec},
+		{"", StatementExec},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.statement, func(t *testing.T) {
+			result := ClassifyStatement(tt.statement)
+			if result != tt.expected {
+				t.Errorf("ClassifyStatement(%q) = %s, expected %s", tt.statement, result, tt.expected)
+			}
+		})
+	}
+}
@@ -3,12 +3,14 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"gosqlpp/internal/config"
 	
+	"github.com/go-sql-driver/mysql"
+
 	// Import database drivers
 	_ "github.com/lib/pq"                // PostgreSQL
-	_ "github.com/go-sql-driver/mysql"   // MySQL/MariaDB
 	_ "github.com/mattn/go-sqlite3"      // SQLite
 	_ "github.com/microsoft/go-mssqldb"  // SQL Server
 )
@@ -18,6 +20,12 @@ type Connection struct {
 	DB     *sql.DB
 	Driver string
 	Name   string
+	// ReadOnly connections only run queries, and their sessions are
+	// read-only where the driver supports it
+	ReadOnly bool
+	// Location is the time zone of date/time values the driver returns as
+	// text, the loc parameter of MySQL connections; nil means UTC
+	Location *time.Location
 }
 
 // Manager handles database connections
@@ -45,7 +53,13 @@ func (m *Manager) Connect(name string, conn config.Connection) error {
 	}
 	
 	// Open database connection
-	db, err := sql.Open(conn.Driver, conn.ConnectionString)
+	var db *sql.DB
+	var err error
+	if conn.ReadOnly {
+		db, err = openReadOnly(conn.Driver, conn.ConnectionString)
+	} else {
+		db, err = sql.Open(conn.Driver, conn.ConnectionString)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to open database connection '%s': %w", name, err)
 	}
@@ -58,14 +72,29 @@ func (m *Manager) Connect(name string, conn config.Connection) error {
 	
 	// Store the connection
 	m.connections[name] = &Connection{
-		DB:     db,
-		Driver: conn.Driver,
-		Name:   name,
+		DB:       db,
+		Driver:   conn.Driver,
+		Name:     name,
+		ReadOnly: conn.ReadOnly,
+		Location: connectionLocation(conn.Driver, conn.ConnectionString),
 	}
 	
 	return nil
 }
 
+// connectionLocation returns the time zone in which the driver reads
+// date/time text, which for MySQL is set by the loc parameter of the DSN
+func connectionLocation(driverName, connectionString string) *time.Location {
+	if driverName != "mysql" {
+		return nil
+	}
+	cfg, err := mysql.ParseDSN(connectionString)
+	if err != nil {
+		return nil
+	}
+	return cfg.Loc
+}
+
 // GetConnection returns a database connection by name
 func (m *Manager) GetConnection(name string) (*Connection, error) {
 	conn, exists := m.connections[name]
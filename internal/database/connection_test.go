@@ -2,6 +2,7 @@ package database
 
 import (
 	"testing"
+	"time"
 
 	"gosqlpp/internal/config"
 )
@@ -134,3 +135,15 @@ func TestListConnections(t *testing.T) {
 		t.Errorf("Expected connection name 'test', got %s", connections[0])
 	}
 }
+
+func TestConnectionLocation(t *testing.T) {
+	if loc := connectionLocation("mysql", "user:pass@tcp(localhost:3306)/app?loc=Local"); loc != time.Local {
+		t.Errorf("Expected the loc parameter to be used, got %v", loc)
+	}
+	if loc := connectionLocation("mysql", "user:pass@tcp(localhost:3306)/app"); loc != time.UTC {
+		t.Errorf("Expected MySQL to default to UTC, got %v", loc)
+	}
+	if loc := connectionLocation("sqlite3", ":memory:"); loc != nil {
+		t.Errorf("Expected no location for SQLite, got %v", loc)
+	}
+}
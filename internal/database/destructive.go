@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AllowDestructiveHint is the hint that lets a reviewed statement run on a
+// protected connection: -- @allow-destructive
+const AllowDestructiveHint = "allow-destructive"
+
+// DestructiveReason explains why a statement, or any statement of a batch,
+// is destructive: DROP, TRUNCATE, DELETE or UPDATE without a WHERE clause, or
+// ALTER ... DROP. It returns an empty string for other statements.
+func DestructiveReason(statement string) string {
+	return statementDestructiveReason(statement, "")
+}
+
+// statementDestructiveReason explains why a statement in a driver's dialect
+// is destructive
+func statementDestructiveReason(statement, driver string) string {
+	for _, part := range executedStatements(statement, driver) {
+		if reason := destructiveReason(part); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// destructiveReason checks a single statement without comments
+func destructiveReason(tokens []Token) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	keyword := tokens[0].Keyword()
+	switch keyword {
+	case "DROP", "TRUNCATE":
+		return keyword + " statement"
+	case "ALTER":
+		if hasTopLevelKeyword(tokens, "DROP") {
+			return "ALTER ... DROP"
+		}
+	case "WITH":
+		// WITH ... DELETE/UPDATE is checked from its DELETE/UPDATE keyword
+		depth := 0
+		for i, tok := range tokens {
+			switch {
+			case tok.IsPunct("("):
+				depth++
+			case tok.IsPunct(")"):
+				depth--
+			case depth == 0 && (tok.Keyword() == "DELETE" || tok.Keyword() == "UPDATE"):
+				return destructiveReason(tokens[i:])
+			}
+		}
+	case "DELETE", "UPDATE":
+		if !hasTopLevelKeyword(tokens, "WHERE") {
+			return keyword + " without WHERE"
+		}
+	}
+	return ""
+}
+
+// SetProtected marks the connection as protected, so destructive statements
+// are refused unless they carry an @allow-destructive hint or destructive
+// statements have been allowed with AllowDestructive
+func (e *Executor) SetProtected(protected bool) {
+	e.protected = protected
+}
+
+// AllowDestructive lets destructive statements run on a protected connection,
+// after they were confirmed or reviewed
+func (e *Executor) AllowDestructive(allowed bool) {
+	e.allowDestructive = allowed
+}
+
+// GuardsDestructive reports whether destructive statements are currently
+// refused. Dry runs never keep their changes, so they are not guarded.
+func (e *Executor) GuardsDestructive() bool {
+	return e.protected && !e.allowDestructive && !e.dryRun
+}
+
+// checkDestructive refuses a destructive statement on a protected connection
+func (e *Executor) checkDestructive(statement string) error {
+	if !e.GuardsDestructive() || ParseHints(statement).Has(AllowDestructiveHint) {
+		return nil
+	}
+	if reason := statementDestructiveReason(statement, e.connection.Driver); reason != "" {
+		return fmt.Errorf("refusing to run destructive statement (%s) on protected connection '%s'; "+
+			"add a -- @%s hint or use --yes-i-mean-it", strings.ToLower(reason), e.connection.Name, AllowDestructiveHint)
+	}
+	return nil
+}
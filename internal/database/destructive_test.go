@@ -0,0 +1,62 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDestructiveReason(t *testing.T) {
+	tests := []struct {
+		statement string
+		reason    string
+	}{
+		{"DROP TABLE users", "DROP statement"},
+		{"truncate table events", "TRUNCATE statement"},
+		{"DELETE FROM users", "DELETE without WHERE"},
+		{"DELETE FROM users WHERE id = 1", ""},
+		{"UPDATE users SET active = 0", "UPDATE without WHERE"},
+		{"UPDATE users SET active = (SELECT 0 FROM t WHERE x = 1)", "UPDATE without WHERE"},
+		{"UPDATE users SET active = 0 WHERE id = 1", ""},
+		{"ALTER TABLE users DROP COLUMN email", "ALTER ... DROP"},
+		{"ALTER TABLE users ADD COLUMN email TEXT", ""},
+		{"WITH old AS (SELECT id FROM users WHERE x = 1) DELETE FROM sessions", "DELETE without WHERE"},
+		{"IF @purge = 1 BEGIN DELETE FROM sessions; END", "DELETE without WHERE"},
+		{"CREATE TRIGGER trg AFTER INSERT ON t BEGIN DELETE FROM sessions; END", ""},
+		{"SELECT 'DROP TABLE users'", ""},
+		{"-- DROP TABLE users\nSELECT 1", ""},
+		{"INSERT INTO t VALUES (1); DROP TABLE t", "DROP statement"},
+	}
+
+	for _, tt := range tests {
+		if got := DestructiveReason(tt.statement); got != tt.reason {
+			t.Errorf("DestructiveReason(%q) = %q, want %q", tt.statement, got, tt.reason)
+		}
+	}
+}
+
+func TestExecuteRefusesDestructiveOnProtectedConnection(t *testing.T) {
+	executor := newTestExecutor(t)
+	executor.Execute("CREATE TABLE users (id INTEGER)", 1, "test.sql")
+	executor.SetProtected(true)
+
+	result := executor.Execute("DELETE FROM users", 2, "test.sql")
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "protected connection") {
+		t.Errorf("Expected DELETE without WHERE to be refused, got %v", result.Error)
+	}
+
+	result = executor.Execute("DELETE FROM users WHERE id = 1", 3, "test.sql")
+	if result.Error != nil {
+		t.Errorf("Expected DELETE with WHERE to run, got %v", result.Error)
+	}
+
+	result = executor.Execute("-- @allow-destructive\nDELETE FROM users", 4, "test.sql")
+	if result.Error != nil {
+		t.Errorf("Expected the @allow-destructive hint to allow the statement, got %v", result.Error)
+	}
+
+	executor.AllowDestructive(true)
+	result = executor.Execute("DROP TABLE users", 5, "test.sql")
+	if result.Error != nil {
+		t.Errorf("Expected destructive statements to run once allowed, got %v", result.Error)
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// dryRunSavepoint names the savepoint that isolates each statement of a dry run
+const dryRunSavepoint = "sqlpp_dry_run"
+
+// SetDryRun enables dry-run mode, in which statements are validated against
+// the database without keeping any of their effects
+func (e *Executor) SetDryRun(enabled bool) {
+	e.dryRun = enabled
+}
+
+// Close releases resources held by the executor. In dry-run mode this rolls
+// back the transaction that holds every validated statement.
+func (e *Executor) Close() error {
+	if e.dryRunTx == nil {
+		return nil
+	}
+	err := e.dryRunTx.Rollback()
+	e.dryRunTx = nil
+	return err
+}
+
+// validate checks a statement without keeping its effects. MySQL commits DDL
+// implicitly, so statements there are only prepared on the server. Other
+// drivers run the statement in a transaction that is rolled back when the
+// executor is closed, so later statements can see objects created by earlier
+// ones; a savepoint around each statement keeps one failure from aborting the
+// transaction.
+func (e *Executor) validate(statement string, result *ExecutionResult) {
+	result.DryRun = true
+
+	// Transaction control would commit or abort the dry-run transaction
+	if hasTransactionControl(statement, e.connection.Driver) {
+		result.Skipped = true
+		return
+	}
+
+	if e.connection.Driver == "mysql" {
+		stmt, err := e.connection.DB.Prepare(statement)
+		if err != nil {
+			result.Error = err
+			return
+		}
+		stmt.Close()
+		return
+	}
+
+	e.validateInTransaction(statement, result)
+}
+
+// validateInTransaction runs a statement inside the dry-run transaction
+func (e *Executor) validateInTransaction(statement string, result *ExecutionResult) {
+	ctx := context.Background()
+
+	if e.dryRunTx == nil {
+		tx, err := e.connection.DB.BeginTx(ctx, nil)
+		if err != nil {
+			result.Error = fmt.Errorf("failed to start dry-run transaction: %w", err)
+			return
+		}
+		e.dryRunTx = tx
+	}
+	tx := e.dryRunTx
+
+	save, rollbackTo, release := savepointStatements(e.connection.Driver)
+	if _, err := tx.ExecContext(ctx, save); err != nil {
+		result.Error = fmt.Errorf("failed to create dry-run savepoint: %w", err)
+		return
+	}
+
+	if err := runDiscardingRows(ctx, tx, statement); err != nil {
+		result.Error = err
+		if _, rbErr := tx.ExecContext(ctx, rollbackTo); rbErr != nil {
+			// The transaction can no longer be used; start a new one for the
+			// next statement
+			e.Close()
+		}
+		return
+	}
+
+	if release != "" {
+		if _, err := tx.ExecContext(ctx, release); err != nil {
+			result.Error = fmt.Errorf("failed to release dry-run savepoint: %w", err)
+		}
+	}
+}
+
+// runDiscardingRows runs a statement, reading at most one row of a query so
+// that errors raised while fetching are reported
+func runDiscardingRows(ctx context.Context, tx *sql.Tx, statement string) error {
+	if ClassifyStatement(statement) != StatementQuery {
+		_, err := tx.ExecContext(ctx, statement)
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rows.Next()
+	return rows.Err()
+}
+
+// savepointStatements returns the statements that create, roll back to and
+// release the dry-run savepoint for a driver
+func savepointStatements(driver string) (save, rollbackTo, release string) {
+	if driver == "sqlserver" {
+		// SQL Server savepoints are released with the transaction
+		return "SAVE TRANSACTION " + dryRunSavepoint, "ROLLBACK TRANSACTION " + dryRunSavepoint, ""
+	}
+	return "SAVEPOINT " + dryRunSavepoint,
+		"ROLLBACK TO SAVEPOINT " + dryRunSavepoint,
+		"RELEASE SAVEPOINT " + dryRunSavepoint
+}
+
+// FormatValidation formats the outcome of validating a statement in a dry run
+func FormatValidation(result *ExecutionResult) string {
+	switch {
+	case result.Error != nil:
+		return FormatError(result)
+	case result.Skipped:
+		return fmt.Sprintf("%s:%d: skipped: transaction control is not run in a dry run",
+			result.FileName, result.LineNumber)
+	default:
+		return fmt.Sprintf("%s:%d: ok", result.FileName, result.LineNumber)
+	}
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDryRunLeavesDatabaseUnchanged(t *testing.T) {
+	executor := newTestExecutor(t)
+	executor.SetDryRun(true)
+
+	statements := []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"INSERT INTO users (name) VALUES ('John')",
+		"SELECT * FROM missing_table",
+		"COMMIT",
+		"SELECT name FROM users",
+	}
+
+	var results []*ExecutionResult
+	for i, statement := range statements {
+		results = append(results, executor.Execute(statement, i+1, "deploy.sql"))
+	}
+
+	// Later statements see objects created by earlier ones
+	for _, i := range []int{0, 1, 4} {
+		if results[i].Error != nil {
+			t.Errorf("Expected statement %d to be valid, got %v", i+1, results[i].Error)
+		}
+		if !results[i].DryRun {
+			t.Errorf("Expected statement %d to be marked as a dry run", i+1)
+		}
+	}
+	if results[2].Error == nil {
+		t.Error("Expected an error for a missing table")
+	}
+	if !results[3].Skipped {
+		t.Error("Expected COMMIT to be skipped")
+	}
+
+	if got := FormatValidation(results[0]); got != "deploy.sql:1: ok" {
+		t.Errorf("Unexpected validation message: %s", got)
+	}
+	if got := FormatValidation(results[2]); !strings.HasPrefix(got, "deploy.sql:3: error:") {
+		t.Errorf("Expected FormatError style message, got %s", got)
+	}
+
+	// Closing rolls everything back
+	if err := executor.Close(); err != nil {
+		t.Fatalf("Expected no error closing executor, got %v", err)
+	}
+	executor.SetDryRun(false)
+	result := executor.Execute("SELECT * FROM users", 6, "deploy.sql")
+	if result.Error == nil {
+		t.Error("Expected users table to be rolled back")
+	}
+}
+
+func TestDryRunValidatesTriggerBodies(t *testing.T) {
+	executor := newTestExecutor(t)
+	executor.SetDryRun(true)
+
+	statements := []string{
+		"CREATE TABLE t (id INTEGER, x INTEGER)",
+		"CREATE TRIGGER trg AFTER INSERT ON t BEGIN UPDATE t SET x = 1 WHERE id = NEW.id; END",
+		"INSERT INTO t (id) VALUES (1)",
+	}
+	for i, statement := range statements {
+		result := executor.Execute(statement, i+1, "deploy.sql")
+		if result.Skipped {
+			t.Errorf("Expected statement %d to be validated, got skipped", i+1)
+		}
+		if result.Error != nil {
+			t.Errorf("Expected statement %d to be valid, got %v", i+1, result.Error)
+		}
+	}
+}
+
+func TestHasTransactionControlBlocks(t *testing.T) {
+	tests := []struct {
+		driver    string
+		statement string
+	}{
+		{"sqlite3", "CREATE TRIGGER trg AFTER INSERT ON t BEGIN UPDATE t SET x = 1; END"},
+		{"mysql", "CREATE PROCEDURE p() BEGIN SELECT 1; END"},
+		{"mysql", "CREATE PROCEDURE p() BEGIN WHILE 1 DO SELECT '#;'; END WHILE; END"},
+		{"sqlserver", "BEGIN UPDATE t SET x = 1; DELETE FROM t WHERE x = 2; END"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.statement, func(t *testing.T) {
+			if hasTransactionControl(tt.statement, tt.driver) {
+				t.Errorf("hasTransactionControl(%q, %q) = true, expected false", tt.statement, tt.driver)
+			}
+		})
+	}
+}
+
+func TestHasTransactionControl(t *testing.T) {
+	tests := []struct {
+		statement string
+		expected  bool
+	}{
+		{"BEGIN", true},
+		{"BEGIN TRANSACTION", true},
+		{"begin immediate", true},
+		{"START TRANSACTION", true},
+		{"COMMIT", true},
+		{"ROLLBACK TO SAVEPOINT a", true},
+		{"SAVE TRANSACTION a", true},
+		{"INSERT INTO t VALUES (1); COMMIT;", true},
+		{"BEGIN TRY SELECT 1 END TRY BEGIN CATCH SELECT 2 END CATCH", false},
+		{"BEGIN TRY SELECT 1; END TRY BEGIN CATCH ROLLBACK; END CATCH", true},
+		{"CREATE TRIGGER trg AFTER INSERT ON t BEGIN UPDATE t SET x = 1; END", false},
+		{"CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; SELECT CASE WHEN 1 THEN 2 END; END", false},
+		{"IF @x = 1 BEGIN UPDATE t SET x = 1; END", false},
+		{"BEGIN; INSERT INTO t VALUES (1); END;", true},
+		{"SELECT 1 -- COMMIT", false},
+		{"UPDATE t SET x = 1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.statement, func(t *testing.T) {
+			if result := HasTransactionControl(tt.statement); result != tt.expected {
+				t.Errorf("HasTransactionControl(%q) = %v, expected %v", tt.statement, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSavepointStatements(t *testing.T) {
+	for _, driver := range []string{"postgres", "sqlite3", "sqlserver"} {
+		save, rollbackTo, _ := savepointStatements(driver)
+		if !strings.Contains(save, dryRunSavepoint) || !strings.Contains(rollbackTo, dryRunSavepoint) {
+			t.Errorf("%s: unexpected savepoint statements %q / %q", driver, save, rollbackTo)
+		}
+	}
+	if _, _, release := savepointStatements("sqlserver"); release != "" {
+		t.Errorf("Expected no release statement for sqlserver, got %q", release)
+	}
+}
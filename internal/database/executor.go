@@ -1,30 +1,121 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"gosqlpp/internal/config"
 )
 
+// ResultSet represents a single set of rows returned by a statement
+type ResultSet struct {
+	Columns     []string
+	ColumnTypes []ColumnType
+	Rows        [][]interface{}
+	RowCount    int64
+	// Truncated marks a result set that had more rows than the row limit
+	Truncated bool
+}
+
 // ExecutionResult represents the result of executing a SQL statement
 type ExecutionResult struct {
 	RowsAffected int64
-	Columns      []string
-	Rows         [][]interface{}
-	Error        error
-	Statement    string
-	LineNumber   int
-	FileName     string
+	// Columns, ColumnTypes and Rows hold the first result set, kept for
+	// callers that only deal with a single set of rows
+	Columns     []string
+	ColumnTypes []ColumnType
+	Rows        [][]interface{}
+	// ResultSets holds every result set returned by the statement, in order
+	ResultSets []ResultSet
+	Error      error
+	Statement  string
+	LineNumber int
+	FileName   string
+	// StartTime and Duration record when the statement ran and how long it took
+	StartTime time.Time
+	Duration  time.Duration
+	// DryRun marks a statement that was only validated; Skipped marks one
+	// that was not validated at all
+	DryRun  bool
+	Skipped bool
+	// Plan holds the query plan when the statement was explained instead of executed
+	Plan *QueryPlan
+	// MaxRows is the row limit applied to each result set, 0 for no limit;
+	// Truncated marks a result with at least one set cut off at the limit
+	MaxRows   int64
+	Truncated bool
+	// Attempts is the number of times the statement was run, more than one
+	// when it was retried after transient errors
+	Attempts int
+}
+
+// GetResultSets returns all result sets of the execution result. Results
+// built by hand with only Columns and Rows are returned as a single set.
+func (r *ExecutionResult) GetResultSets() []ResultSet {
+	if len(r.ResultSets) > 0 {
+		return r.ResultSets
+	}
+	if len(r.Columns) == 0 && len(r.Rows) == 0 {
+		return nil
+	}
+	return []ResultSet{{
+		Columns:     r.Columns,
+		ColumnTypes: r.ColumnTypes,
+		Rows:        r.Rows,
+		RowCount:    int64(len(r.Rows)),
+	}}
+}
+
+// setPrimaryResultSet mirrors the first result set in Columns, ColumnTypes and Rows
+func (r *ExecutionResult) setPrimaryResultSet() {
+	if len(r.ResultSets) > 0 {
+		r.Columns = r.ResultSets[0].Columns
+		r.ColumnTypes = r.ResultSets[0].ColumnTypes
+		r.Rows = r.ResultSets[0].Rows
+	}
+}
+
+// TotalRows returns the number of rows across all result sets
+func (r *ExecutionResult) TotalRows() int64 {
+	var total int64
+	for _, set := range r.GetResultSets() {
+		total += set.RowCount
+	}
+	return total
 }
 
 // Executor handles SQL statement execution
 type Executor struct {
 	connection *Connection
+	// dryRun validates statements without keeping their effects; dryRunTx
+	// holds every statement validated so far
+	dryRun   bool
+	dryRunTx *sql.Tx
+	// retry retries statements after transient errors; inTransaction tracks
+	// explicit transactions, in which statements are never retried
+	retry         *RetryPolicy
+	inTransaction bool
+	logWriter     io.Writer
+	sleep         func(time.Duration)
+	// maxRows limits the rows fetched per result set, 0 for no limit
+	maxRows int64
+	// protected refuses destructive statements unless allowDestructive is set
+	protected        bool
+	allowDestructive bool
 }
 
 // NewExecutor creates a new SQL executor for the given connection
 func NewExecutor(conn *Connection) *Executor {
 	return &Executor{
 		connection: conn,
+		logWriter:  os.Stderr,
+		sleep:      time.Sleep,
 	}
 }
 
@@ -42,15 +133,83 @@ func (e *Executor) Execute(statement string, lineNumber int, fileName string) *E
 		return result
 	}
 	
+	result.StartTime = time.Now()
+	if result.MaxRows, result.Error = e.rowLimit(statement); result.Error != nil {
+		return result
+	}
+	if result.Error = checkOutputHint(statement); result.Error != nil {
+		return result
+	}
+	if result.Error = e.checkReadOnly(statement); result.Error != nil {
+		return result
+	}
+	if result.Error = e.checkDestructive(statement); result.Error != nil {
+		return result
+	}
+	if e.dryRun {
+		e.validate(statement, result)
+	} else {
+		e.runWithRetry(statement, result)
+	}
+	result.Duration = time.Since(result.StartTime)
+	return result
+}
+
+// SetMaxRows limits the rows fetched for each result set; 0 means no limit.
+// A statement can override the limit with a -- @max-rows N hint.
+func (e *Executor) SetMaxRows(limit int64) {
+	e.maxRows = limit
+}
+
+// rowLimit returns the row limit for a statement, taking its @max-rows hint
+// into account
+func (e *Executor) rowLimit(statement string) (int64, error) {
+	value, ok := ParseHints(statement)["max-rows"]
+	if !ok {
+		return e.maxRows, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid @max-rows hint %q, expected 0 (no limit) or more", value)
+	}
+	return limit, nil
+}
+
+// checkOutputHint validates the format named by a statement's @output hint
+// before the statement runs
+func checkOutputHint(statement string) error {
+	value := ParseHints(statement)["output"]
+	if value == "" {
+		return nil
+	}
+	return config.ValidateOutputFormat(strings.ToLower(value))
+}
+
+// run dispatches a statement to Query or Exec based on its classification
+func (e *Executor) run(statement string, result *ExecutionResult) *ExecutionResult {
+	kind := classifyStatement(statement, e.connection.Driver)
+
+	// The sqlite3 driver only runs the last statement of a batch passed to
+	// Query, so batches mixing queries with other statements are split up
+	if kind == StatementAmbiguous && e.connection.Driver == "sqlite3" {
+		if parts := splitStatements(statement, e.connection.Driver); len(parts) > 1 {
+			return e.executeBatch(parts, result)
+		}
+	}
+
 	// Determine if this is a query or an execution statement
-	if isQueryStatement(statement) {
+	switch kind {
+	case StatementQuery:
 		return e.executeQuery(statement, result)
-	} else {
+	case StatementAmbiguous:
+		return e.executeAmbiguous(statement, result)
+	default:
 		return e.executeStatement(statement, result)
 	}
 }
 
-// executeQuery executes a SELECT statement and returns rows
+// executeQuery executes a SELECT statement and returns rows from every
+// result set the statement produces
 func (e *Executor) executeQuery(statement string, result *ExecutionResult) *ExecutionResult {
 	rows, err := e.connection.DB.Query(statement)
 	if err != nil {
@@ -58,48 +217,100 @@ func (e *Executor) executeQuery(statement string, result *ExecutionResult) *Exec
 		return result
 	}
 	defer rows.Close()
-	
+
+	if err := readResultSets(rows, result, e.connection.Location); err != nil {
+		result.Error = err
+	}
+	return result
+}
+
+// readResultSets reads every result set from rows into the result, keeping
+// at most result.MaxRows rows of each set
+func readResultSets(rows *sql.Rows, result *ExecutionResult, loc *time.Location) error {
+	for {
+		set, err := readResultSet(rows, result.MaxRows, loc)
+		if err != nil {
+			return err
+		}
+		if set.Truncated {
+			result.Truncated = true
+		}
+		// Result sets without columns come from statements in a batch that
+		// do not return rows, so there is nothing to display for them
+		if len(set.Columns) > 0 {
+			result.ResultSets = append(result.ResultSets, set)
+		}
+
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	result.setPrimaryResultSet()
+	return nil
+}
+
+// readResultSet reads the columns and rows of the current result set. With a
+// limit above zero, reading stops after limit rows and the set is marked as
+// truncated when more rows follow.
+func readResultSet(rows *sql.Rows, limit int64, loc *time.Location) (ResultSet, error) {
+	var set ResultSet
+
 	// Get column information
 	columns, err := rows.Columns()
 	if err != nil {
-		result.Error = err
-		return result
+		return set, err
 	}
-	result.Columns = columns
-	
-	// Read all rows
-	var allRows [][]interface{}
+	set.Columns = columns
+
+	// Get column type information; drivers that cannot report it fall back
+	// to names only
+	if types, err := rows.ColumnTypes(); err == nil && len(types) == len(columns) {
+		set.ColumnTypes = newColumnTypes(types)
+	} else {
+		set.ColumnTypes = make([]ColumnType, len(columns))
+		for i, name := range columns {
+			set.ColumnTypes[i] = ColumnType{Name: name}
+		}
+	}
+
+	// Read all rows, up to the limit
 	for rows.Next() {
+		if limit > 0 && int64(len(set.Rows)) >= limit {
+			set.Truncated = true
+			break
+		}
+
 		// Create a slice of interface{} to hold the values
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
 		for i := range values {
 			valuePtrs[i] = &values[i]
 		}
-		
+
 		// Scan the row
 		if err := rows.Scan(valuePtrs...); err != nil {
-			result.Error = err
-			return result
+			return set, err
 		}
-		
-		// Convert byte slices to strings for better display
+
+		// Convert raw driver values using the column types
 		for i, val := range values {
-			if b, ok := val.([]byte); ok {
-				values[i] = string(b)
-			}
+			values[i] = normalizeValue(val, set.ColumnTypes[i], loc)
 		}
-		
-		allRows = append(allRows, values)
+
+		set.Rows = append(set.Rows, values)
 	}
-	
+
 	if err := rows.Err(); err != nil {
-		result.Error = err
-		return result
+		return set, err
 	}
-	
-	result.Rows = allRows
-	return result
+
+	set.RowCount = int64(len(set.Rows))
+	return set, nil
 }
 
 // executeStatement executes a non-query statement (INSERT, UPDATE, DELETE, etc.)
@@ -118,28 +329,83 @@ func (e *Executor) executeStatement(statement string, result *ExecutionResult) *
 	return result
 }
 
-// isQueryStatement determines if a SQL statement is a query (SELECT) or not
-func isQueryStatement(statement string) bool {
-	// Normalize the statement
-	normalized := strings.TrimSpace(strings.ToUpper(statement))
-	
-	// Check for common query keywords
-	queryKeywords := []string{
-		"SELECT",
-		"WITH",     // Common Table Expressions
-		"SHOW",     // MySQL/PostgreSQL specific
-		"DESCRIBE", // MySQL specific
-		"DESC",     // MySQL specific
-		"EXPLAIN",  // Query execution plans
+// executeBatch runs each statement of a batch separately and merges the
+// results. Each statement is retried on its own, so statements that already
+// succeeded are never run again.
+func (e *Executor) executeBatch(parts []string, result *ExecutionResult) *ExecutionResult {
+	for _, part := range parts {
+		partResult := &ExecutionResult{FileName: result.FileName, LineNumber: result.LineNumber, MaxRows: result.MaxRows}
+		e.runWithRetry(part, partResult)
+		result.Attempts = max(result.Attempts, partResult.Attempts)
+		result.ResultSets = append(result.ResultSets, partResult.ResultSets...)
+		result.Truncated = result.Truncated || partResult.Truncated
+		result.RowsAffected += partResult.RowsAffected
+		if partResult.Error != nil {
+			result.Error = partResult.Error
+			break
+		}
 	}
-	
-	for _, keyword := range queryKeywords {
-		if strings.HasPrefix(normalized, keyword) {
-			return true
+
+	result.setPrimaryResultSet()
+	return result
+}
+
+// executeAmbiguous runs a statement that may or may not return rows through
+// Query. When no columns come back, the affected row count is read back on the
+// same session for drivers that can report it after the fact.
+func (e *Executor) executeAmbiguous(statement string, result *ExecutionResult) *ExecutionResult {
+	ctx := context.Background()
+	conn, err := e.connection.DB.Conn(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer conn.Close()
+
+	// SQLite only keeps a running total, so take a reading before the statement
+	var before int64
+	if e.connection.Driver == "sqlite3" {
+		before, _ = e.changeCounter(ctx, conn)
+	}
+
+	rows, err := conn.QueryContext(ctx, statement)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	err = readResultSets(rows, result, e.connection.Location)
+	rows.Close()
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if len(result.ResultSets) == 0 {
+		if after, ok := e.changeCounter(ctx, conn); ok && after >= before {
+			result.RowsAffected = after - before
 		}
 	}
-	
-	return false
+	return result
+}
+
+// changeCounter reads the session's change counter for drivers that have one:
+// a running total of changed rows for SQLite, the last statement's count for MySQL
+func (e *Executor) changeCounter(ctx context.Context, conn *sql.Conn) (int64, bool) {
+	var query string
+	switch e.connection.Driver {
+	case "sqlite3":
+		query = "SELECT total_changes()"
+	case "mysql":
+		query = "SELECT ROW_COUNT()"
+	default:
+		return 0, false
+	}
+
+	var count int64
+	if err := conn.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, false
+	}
+	return count, true
 }
 
 // FormatError formats a database error with file and line information
@@ -158,8 +424,8 @@ func FormatRowsAffected(result *ExecutionResult) string {
 		return ""
 	}
 	
-	if len(result.Rows) > 0 {
-		return fmt.Sprintf("(%d rows)", len(result.Rows))
+	if sets := result.GetResultSets(); len(sets) > 0 {
+		return fmt.Sprintf("(%d rows)", result.TotalRows())
 	}
 	
 	if result.RowsAffected > 0 {
@@ -168,3 +434,13 @@ func FormatRowsAffected(result *ExecutionResult) string {
 	
 	return "(0 rows affected)"
 }
+
+// FormatTruncation formats the notice for a result cut off at the row limit
+func FormatTruncation(result *ExecutionResult) string {
+	return fmt.Sprintf("(truncated at %d rows)", result.MaxRows)
+}
+
+// FormatTiming formats the elapsed time of a statement
+func FormatTiming(result *ExecutionResult) string {
+	return fmt.Sprintf("Time: %.3f ms", float64(result.Duration.Microseconds())/1000)
+}
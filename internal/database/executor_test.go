@@ -0,0 +1,238 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/config"
+)
+
+// newTestExecutor creates an executor backed by an in-memory SQLite database
+func newTestExecutor(t *testing.T) *Executor {
+	t.Helper()
+
+	manager := NewManager()
+	t.Cleanup(func() { manager.CloseAll() })
+
+	conn := config.Connection{
+		Driver:           "sqlite3",
+		ConnectionString: ":memory:",
+	}
+	if err := manager.Connect("test", conn); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	dbConn, err := manager.GetConnection("test")
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	// Keep a single connection so the in-memory database survives between statements
+	dbConn.DB.SetMaxOpenConns(1)
+
+	return NewExecutor(dbConn)
+}
+
+func TestExecuteQueryResultSets(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	result := executor.Execute("SELECT 1 AS id, 'John' AS name", 1, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected no error, got %v", result.Error)
+	}
+
+	if len(result.ResultSets) != 1 {
+		t.Fatalf("Expected 1 result set, got %d", len(result.ResultSets))
+	}
+
+	set := result.ResultSets[0]
+	if len(set.Columns) != 2 || set.Columns[0] != "id" || set.Columns[1] != "name" {
+		t.Errorf("Unexpected columns: %v", set.Columns)
+	}
+	if set.RowCount != 1 {
+		t.Errorf("Expected row count 1, got %d", set.RowCount)
+	}
+
+	// The first result set is mirrored in Columns and Rows
+	if len(result.Rows) != 1 || len(result.Columns) != 2 {
+		t.Errorf("Expected first result set in Columns/Rows, got %v / %v", result.Columns, result.Rows)
+	}
+}
+
+func TestGetResultSets(t *testing.T) {
+	// Results built by hand are returned as a single set
+	result := &ExecutionResult{
+		Columns: []string{"id"},
+		Rows:    [][]interface{}{{1}, {2}},
+	}
+
+	sets := result.GetResultSets()
+	if len(sets) != 1 {
+		t.Fatalf("Expected 1 result set, got %d", len(sets))
+	}
+	if sets[0].RowCount != 2 {
+		t.Errorf("Expected row count 2, got %d", sets[0].RowCount)
+	}
+
+	// Multiple result sets are counted together
+	result = &ExecutionResult{
+		ResultSets: []ResultSet{
+			{Columns: []string{"a"}, Rows: [][]interface{}{{1}}, RowCount: 1},
+			{Columns: []string{"b"}, Rows: [][]interface{}{{2}, {3}}, RowCount: 2},
+		},
+	}
+	if total := result.TotalRows(); total != 3 {
+		t.Errorf("Expected 3 total rows, got %d", total)
+	}
+	if msg := FormatRowsAffected(result); msg != "(3 rows)" {
+		t.Errorf("Expected '(3 rows)', got %s", msg)
+	}
+
+	// Results without rows have no result sets
+	if sets := (&ExecutionResult{RowsAffected: 2}).GetResultSets(); sets != nil {
+		t.Errorf("Expected no result sets, got %v", sets)
+	}
+}
+
+func TestExecuteRowReturningStatements(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	setup := executor.Execute("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)", 1, "test.sql")
+	if setup.Error != nil {
+		t.Fatalf("Failed to create table: %v", setup.Error)
+	}
+
+	// INSERT ... RETURNING is displayed as rows
+	result := executor.Execute("INSERT INTO users (name) VALUES ('John') RETURNING id, name", 2, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected no error, got %v", result.Error)
+	}
+	if len(result.Rows) != 1 || result.Columns[1] != "name" {
+		t.Errorf("Expected returned row, got %v %v", result.Columns, result.Rows)
+	}
+
+	// Leading comments do not hide a query
+	result = executor.Execute("-- list users\nSELECT name FROM users", 3, "test.sql")
+	if result.Error != nil || len(result.Rows) != 1 {
+		t.Errorf("Expected 1 row, got %v (error %v)", result.Rows, result.Error)
+	}
+
+	// PRAGMA reports rows
+	result = executor.Execute("PRAGMA table_info(users)", 4, "test.sql")
+	if result.Error != nil || len(result.Rows) != 2 {
+		t.Errorf("Expected 2 column rows, got %v (error %v)", result.Rows, result.Error)
+	}
+
+	// Ambiguous statements without rows fall back to the affected row count
+	result = executor.Execute("PRAGMA user_version = 1", 5, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected no error, got %v", result.Error)
+	}
+	if len(result.ResultSets) != 0 || result.RowsAffected != 0 {
+		t.Errorf("Expected no rows affected and no result sets, got %d / %v", result.RowsAffected, result.ResultSets)
+	}
+
+	// Batches mixing DML and queries run every statement
+	result = executor.Execute("INSERT INTO users (name) VALUES ('a'); INSERT INTO users (name) VALUES ('b'); SELECT count(*) AS n FROM users", 6, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected no error, got %v", result.Error)
+	}
+	if result.RowsAffected != 2 || len(result.Rows) != 1 || result.Rows[0][0] != int64(3) {
+		t.Errorf("Expected 2 rows affected and a count of 3, got %d / %v", result.RowsAffected, result.Rows)
+	}
+}
+
+func TestExecuteColumnTypes(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	setup := executor.Execute(`CREATE TABLE items (id INTEGER NOT NULL, price DECIMAL(10,2), active BOOLEAN, data BLOB);
+INSERT INTO items VALUES (1, 9.99, 1, x'00ff')`, 1, "test.sql")
+	if setup.Error != nil {
+		t.Fatalf("Failed to set up table: %v", setup.Error)
+	}
+
+	result := executor.Execute("SELECT id, price, active, data FROM items", 2, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected no error, got %v", result.Error)
+	}
+
+	if len(result.ColumnTypes) != 4 {
+		t.Fatalf("Expected 4 column types, got %d", len(result.ColumnTypes))
+	}
+	if result.ColumnTypes[0].Name != "id" || result.ColumnTypes[0].DatabaseType != "INTEGER" {
+		t.Errorf("Unexpected column type for id: %+v", result.ColumnTypes[0])
+	}
+	if result.ColumnTypes[3].DatabaseType != "BLOB" {
+		t.Errorf("Expected BLOB column type, got %s", result.ColumnTypes[3].DatabaseType)
+	}
+
+	row := result.Rows[0]
+	if row[2] != true {
+		t.Errorf("Expected BOOLEAN column to be true, got %#v", row[2])
+	}
+	if data, ok := row[3].(Binary); !ok || len(data) != 2 {
+		t.Errorf("Expected BLOB column as Binary, got %#v", row[3])
+	}
+}
+
+func TestExecuteRecordsTiming(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	result := executor.Execute("SELECT 1", 1, "test.sql")
+	if result.StartTime.IsZero() {
+		t.Error("Expected start time to be recorded")
+	}
+	if result.Duration <= 0 {
+		t.Errorf("Expected positive duration, got %v", result.Duration)
+	}
+	if !strings.HasPrefix(FormatTiming(result), "Time: ") {
+		t.Errorf("Unexpected timing format: %s", FormatTiming(result))
+	}
+}
+
+func TestExecuteMaxRows(t *testing.T) {
+	executor := newTestExecutor(t)
+	executor.SetMaxRows(2)
+
+	query := "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 5) SELECT x FROM n"
+	result := executor.Execute(query, 1, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected no error, got %v", result.Error)
+	}
+	if !result.Truncated || len(result.Rows) != 2 || result.MaxRows != 2 {
+		t.Errorf("Expected 2 rows and a truncated result, got %d rows (truncated=%v)", len(result.Rows), result.Truncated)
+	}
+
+	// Results that fit within the limit are not truncated
+	result = executor.Execute("SELECT 1 UNION ALL SELECT 2", 2, "test.sql")
+	if result.Truncated || len(result.Rows) != 2 {
+		t.Errorf("Expected 2 rows without truncation, got %d rows (truncated=%v)", len(result.Rows), result.Truncated)
+	}
+
+	// A hint overrides the limit for a single statement
+	result = executor.Execute("-- @max-rows 0\n"+query, 3, "test.sql")
+	if result.Truncated || len(result.Rows) != 5 {
+		t.Errorf("Expected the @max-rows 0 hint to lift the limit, got %d rows", len(result.Rows))
+	}
+
+	result = executor.Execute("-- @max-rows lots\n"+query, 4, "test.sql")
+	if result.Error == nil {
+		t.Error("Expected an invalid @max-rows hint to be reported")
+	}
+}
+
+func TestExecuteValidatesOutputHint(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	result := executor.Execute("-- @output CSV\nSELECT 1", 1, "test.sql")
+	if result.Error != nil {
+		t.Errorf("Expected a supported @output hint to be accepted, got %v", result.Error)
+	}
+
+	result = executor.Execute("-- @output jsn\nSELECT 1", 2, "test.sql")
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "invalid output format 'jsn', must be one of: table, json") {
+		t.Errorf("Expected an invalid @output hint to be reported, got %v", result.Error)
+	}
+	if len(result.Rows) != 0 {
+		t.Error("Expected the statement not to run with an invalid @output hint")
+	}
+}
@@ -0,0 +1,412 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PlanNode is one operation of a query plan
+type PlanNode struct {
+	Operation  string            `json:"operation" yaml:"operation"`
+	Properties map[string]string `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Children   []*PlanNode       `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// QueryPlan is a driver-independent query plan for a statement
+type QueryPlan struct {
+	Driver string      `json:"driver" yaml:"driver"`
+	Nodes  []*PlanNode `json:"plan" yaml:"plan"`
+}
+
+// explainKeywords start statements that have a query plan
+var explainKeywords = map[string]bool{
+	"SELECT": true,
+	"WITH":   true,
+	"VALUES": true,
+	"INSERT": true,
+	"UPDATE": true,
+	"DELETE": true,
+	"MERGE":  true,
+}
+
+// IsExplainable reports whether a statement is a single SELECT or DML
+// statement that can be run through the driver's plan command
+func IsExplainable(statement string) bool {
+	parts := splitTokenStatements(significantTokens(Tokenize(statement)))
+	if len(parts) != 1 {
+		return false
+	}
+
+	tokens := parts[0]
+	for len(tokens) > 0 && tokens[0].IsPunct("(") {
+		tokens = tokens[1:]
+	}
+	return len(tokens) > 0 && explainKeywords[tokens[0].Keyword()]
+}
+
+// Explain returns the query plan of a statement without executing it
+func (e *Executor) Explain(statement string, lineNumber int, fileName string) *ExecutionResult {
+	result := &ExecutionResult{
+		Statement:  statement,
+		LineNumber: lineNumber,
+		FileName:   fileName,
+		StartTime:  time.Now(),
+	}
+
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	var nodes []*PlanNode
+	var err error
+
+	switch e.connection.Driver {
+	case "sqlite3":
+		nodes, err = e.explainSQLite(statement)
+	case "postgres":
+		nodes, err = e.explainJSON("EXPLAIN (FORMAT JSON) "+statement, parsePostgresPlan)
+	case "mysql":
+		nodes, err = e.explainJSON("EXPLAIN FORMAT=JSON "+statement, parseMySQLPlan)
+	case "sqlserver":
+		nodes, err = e.explainSQLServer(statement)
+	default:
+		err = fmt.Errorf("query plans are not supported for driver: %s", e.connection.Driver)
+	}
+
+	result.Duration = time.Since(result.StartTime)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.Plan = &QueryPlan{Driver: e.connection.Driver, Nodes: nodes}
+	return result
+}
+
+// explainSQLite reads EXPLAIN QUERY PLAN rows, which link each step to its parent by id
+func (e *Executor) explainSQLite(statement string) ([]*PlanNode, error) {
+	rows, err := e.connection.DB.Query("EXPLAIN QUERY PLAN " + statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set, err := readResultSet(rows, 0, e.connection.Location)
+	if err != nil {
+		return nil, err
+	}
+	return buildSQLitePlan(set)
+}
+
+// buildSQLitePlan builds a plan tree from EXPLAIN QUERY PLAN rows
+func buildSQLitePlan(set ResultSet) ([]*PlanNode, error) {
+	idCol, parentCol, detailCol := -1, -1, -1
+	for i, col := range set.Columns {
+		switch strings.ToLower(col) {
+		case "id":
+			idCol = i
+		case "parent":
+			parentCol = i
+		case "detail":
+			detailCol = i
+		}
+	}
+	if idCol < 0 || parentCol < 0 || detailCol < 0 {
+		return nil, fmt.Errorf("unexpected EXPLAIN QUERY PLAN columns: %v", set.Columns)
+	}
+
+	var roots []*PlanNode
+	nodes := make(map[string]*PlanNode)
+	for _, row := range set.Rows {
+		node := &PlanNode{Operation: fmt.Sprint(row[detailCol])}
+		nodes[fmt.Sprint(row[idCol])] = node
+
+		if parent, ok := nodes[fmt.Sprint(row[parentCol])]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// explainJSON runs a plan command that returns a single JSON document
+func (e *Executor) explainJSON(query string, parse func([]byte) ([]*PlanNode, error)) ([]*PlanNode, error) {
+	var document []byte
+	if err := e.connection.DB.QueryRow(query).Scan(&document); err != nil {
+		return nil, err
+	}
+	return parse(document)
+}
+
+// parsePostgresPlan converts EXPLAIN (FORMAT JSON) output, an array of
+// {"Plan": {...}} objects whose nodes nest through "Plans"
+func parsePostgresPlan(document []byte) ([]*PlanNode, error) {
+	var plans []map[string]interface{}
+	if err := json.Unmarshal(document, &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+
+	var nodes []*PlanNode
+	for _, plan := range plans {
+		if root, ok := plan["Plan"].(map[string]interface{}); ok {
+			nodes = append(nodes, postgresPlanNode(root))
+		}
+	}
+	return nodes, nil
+}
+
+// postgresPlanNode converts a postgres plan node and its sub-plans
+func postgresPlanNode(object map[string]interface{}) *PlanNode {
+	node := &PlanNode{
+		Operation:  fmt.Sprint(object["Node Type"]),
+		Properties: make(map[string]string),
+	}
+
+	for key, value := range object {
+		switch key {
+		case "Node Type":
+		case "Plans":
+			children, _ := value.([]interface{})
+			for _, child := range children {
+				if childObject, ok := child.(map[string]interface{}); ok {
+					node.Children = append(node.Children, postgresPlanNode(childObject))
+				}
+			}
+		default:
+			node.Properties[key] = planValue(value)
+		}
+	}
+	return node
+}
+
+// parseMySQLPlan converts EXPLAIN FORMAT=JSON output. Objects holding only
+// scalar values (such as cost_info) become properties of their parent; other
+// nested objects become child operations named after their key.
+func parseMySQLPlan(document []byte) ([]*PlanNode, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(document, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+	return mysqlPlanNodes(root), nil
+}
+
+// mysqlPlanNodes converts the object-valued keys of a MySQL plan object to nodes
+func mysqlPlanNodes(object map[string]interface{}) []*PlanNode {
+	var nodes []*PlanNode
+	for _, key := range sortedKeys(object) {
+		switch value := object[key].(type) {
+		case map[string]interface{}:
+			if !isScalarObject(value) {
+				nodes = append(nodes, mysqlPlanNode(key, value))
+			}
+		case []interface{}:
+			for _, item := range value {
+				if itemObject, ok := item.(map[string]interface{}); ok {
+					nodes = append(nodes, mysqlPlanNode(key, itemObject))
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+// mysqlPlanNode converts a MySQL plan object to a node named after its key
+func mysqlPlanNode(key string, object map[string]interface{}) *PlanNode {
+	node := &PlanNode{Operation: key, Properties: make(map[string]string)}
+	if tableName, ok := object["table_name"]; ok {
+		node.Operation = fmt.Sprintf("%s %v", key, tableName)
+	}
+
+	for name, value := range object {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if isScalarObject(v) {
+				for subName, subValue := range v {
+					node.Properties[name+"."+subName] = planValue(subValue)
+				}
+			}
+		case []interface{}:
+			if !containsObjects(v) {
+				node.Properties[name] = planValue(v)
+			}
+		default:
+			node.Properties[name] = planValue(v)
+		}
+	}
+	node.Children = mysqlPlanNodes(object)
+	return node
+}
+
+// explainSQLServer reads the XML showplan of a statement. SHOWPLAN_XML is a
+// session setting, so it is switched on and off on a dedicated connection.
+func (e *Executor) explainSQLServer(statement string) ([]*PlanNode, error) {
+	ctx := context.Background()
+	conn, err := e.connection.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET SHOWPLAN_XML ON"); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(ctx, "SET SHOWPLAN_XML OFF")
+
+	rows, err := conn.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []*PlanNode
+	for {
+		for rows.Next() {
+			var document string
+			if err := rows.Scan(&document); err != nil {
+				return nil, err
+			}
+			planNodes, err := parseSQLServerPlan(strings.NewReader(document))
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, planNodes...)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return nodes, rows.Err()
+}
+
+// parseSQLServerPlan converts a showplan XML document. Each statement becomes
+// a root node, and RelOp elements become operations nested like the XML.
+func parseSQLServerPlan(reader io.Reader) ([]*PlanNode, error) {
+	decoder := xml.NewDecoder(reader)
+	var roots []*PlanNode
+	// stack holds the node owning each open element, or nil for elements
+	// that are not plan nodes
+	var stack []*PlanNode
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query plan: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var node *PlanNode
+			switch t.Name.Local {
+			case "StmtSimple", "StmtCond", "StmtCursor":
+				node = &PlanNode{Operation: "Statement", Properties: xmlAttributes(t, "StatementText", "StatementType", "StatementSubTreeCost", "StatementEstRows")}
+			case "RelOp":
+				node = &PlanNode{Operation: attributeValue(t, "PhysicalOp"), Properties: xmlAttributes(t, "LogicalOp", "EstimateRows", "EstimatedTotalSubtreeCost")}
+			case "Object":
+				if parent := nearestNode(stack); parent != nil {
+					parent.Properties["Object"] = strings.Trim(attributeValue(t, "Table"), "[]")
+					if index := attributeValue(t, "Index"); index != "" {
+						parent.Properties["Index"] = strings.Trim(index, "[]")
+					}
+				}
+			}
+
+			if node != nil {
+				if parent := nearestNode(stack); parent != nil {
+					parent.Children = append(parent.Children, node)
+				} else {
+					roots = append(roots, node)
+				}
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return roots, nil
+}
+
+// nearestNode returns the innermost plan node on the element stack
+func nearestNode(stack []*PlanNode) *PlanNode {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] != nil {
+			return stack[i]
+		}
+	}
+	return nil
+}
+
+// xmlAttributes collects the named attributes that are present on an element
+func xmlAttributes(element xml.StartElement, names ...string) map[string]string {
+	properties := make(map[string]string)
+	for _, name := range names {
+		if value := attributeValue(element, name); value != "" {
+			properties[name] = value
+		}
+	}
+	return properties
+}
+
+// attributeValue returns the value of an attribute, or "" when absent
+func attributeValue(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// planValue formats a JSON plan value as a property string
+func planValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = planValue(item)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isScalarObject reports whether every value of an object is a scalar
+func isScalarObject(object map[string]interface{}) bool {
+	for _, value := range object {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// containsObjects reports whether a JSON array holds objects
+func containsObjects(values []interface{}) bool {
+	for _, value := range values {
+		if _, ok := value.(map[string]interface{}); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of an object in a stable order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsExplainable(t *testing.T) {
+	tests := []struct {
+		statement string
+		expected  bool
+	}{
+		{"SELECT * FROM users", true},
+		{"-- comment\nUPDATE users SET name = 'x'", true},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"DELETE FROM users WHERE id = 1;", true},
+		{"CREATE TABLE t (id INTEGER)", false},
+		{"SELECT 1; SELECT 2", false},
+		{"EXPLAIN SELECT 1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.statement, func(t *testing.T) {
+			if result := IsExplainable(tt.statement); result != tt.expected {
+				t.Errorf("IsExplainable(%q) = %v, expected %v", tt.statement, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExplainSQLite(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	setup := executor.Execute(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER)`, 1, "test.sql")
+	if setup.Error != nil {
+		t.Fatalf("Failed to set up tables: %v", setup.Error)
+	}
+
+	result := executor.Explain("SELECT * FROM users WHERE id IN (SELECT user_id FROM orders);", 3, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected no error, got %v", result.Error)
+	}
+	if result.Plan == nil || result.Plan.Driver != "sqlite3" || len(result.Plan.Nodes) == 0 {
+		t.Fatalf("Expected a sqlite3 plan, got %+v", result.Plan)
+	}
+
+	var operations []string
+	var walk func(nodes []*PlanNode)
+	walk = func(nodes []*PlanNode) {
+		for _, node := range nodes {
+			operations = append(operations, node.Operation)
+			walk(node.Children)
+		}
+	}
+	walk(result.Plan.Nodes)
+
+	joined := strings.Join(operations, "\n")
+	if !strings.Contains(joined, "users") || !strings.Contains(joined, "orders") {
+		t.Errorf("Expected plan to mention both tables, got:\n%s", joined)
+	}
+
+	// Explaining does not execute the statement
+	executor.Explain("DELETE FROM users", 4, "test.sql")
+	executor.Execute("INSERT INTO users (name) VALUES ('John')", 5, "test.sql")
+	explained := executor.Explain("DELETE FROM users", 6, "test.sql")
+	if explained.Error != nil {
+		t.Fatalf("Expected no error, got %v", explained.Error)
+	}
+	count := executor.Execute("SELECT count(*) FROM users", 7, "test.sql")
+	if count.Rows[0][0] != int64(1) {
+		t.Errorf("Expected DELETE not to run, got count %v", count.Rows[0][0])
+	}
+}
+
+func TestBuildSQLitePlan(t *testing.T) {
+	set := ResultSet{
+		Columns: []string{"id", "parent", "notused", "detail"},
+		Rows: [][]interface{}{
+			{int64(2), int64(0), int64(0), "SCAN users"},
+			{int64(5), int64(0), int64(0), "LIST SUBQUERY 1"},
+			{int64(7), int64(5), int64(0), "SCAN orders"},
+		},
+	}
+
+	nodes, err := buildSQLitePlan(set)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(nodes) != 2 || nodes[1].Operation != "LIST SUBQUERY 1" {
+		t.Fatalf("Unexpected roots: %+v", nodes)
+	}
+	if len(nodes[1].Children) != 1 || nodes[1].Children[0].Operation != "SCAN orders" {
+		t.Errorf("Expected SCAN orders under the subquery, got %+v", nodes[1].Children)
+	}
+}
+
+func TestParsePostgresPlan(t *testing.T) {
+	document := `[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Total Cost": 12.5,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Parent Relationship": "Outer"},
+			{"Node Type": "Hash", "Plans": [{"Node Type": "Index Scan", "Index Name": "users_pkey"}]}
+		]}}]`
+
+	nodes, err := parsePostgresPlan([]byte(document))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Operation != "Hash Join" {
+		t.Fatalf("Unexpected root: %+v", nodes)
+	}
+	root := nodes[0]
+	if root.Properties["Join Type"] != "Inner" || root.Properties["Total Cost"] != "12.5" {
+		t.Errorf("Unexpected root properties: %v", root.Properties)
+	}
+	if len(root.Children) != 2 || root.Children[0].Properties["Relation Name"] != "orders" {
+		t.Errorf("Unexpected children: %+v", root.Children)
+	}
+	if root.Children[1].Children[0].Operation != "Index Scan" {
+		t.Errorf("Expected nested Index Scan, got %+v", root.Children[1].Children)
+	}
+}
+
+func TestParseMySQLPlan(t *testing.T) {
+	document := `{"query_block": {"select_id": 1, "cost_info": {"query_cost": "1.20"},
+		"table": {"table_name": "users", "access_type": "ALL", "used_columns": ["id", "name"]}}}`
+
+	nodes, err := parseMySQLPlan([]byte(document))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Operation != "query_block" {
+		t.Fatalf("Unexpected root: %+v", nodes)
+	}
+	block := nodes[0]
+	if block.Properties["cost_info.query_cost"] != "1.20" || block.Properties["select_id"] != "1" {
+		t.Errorf("Unexpected properties: %v", block.Properties)
+	}
+	if len(block.Children) != 1 || block.Children[0].Operation != "table users" {
+		t.Fatalf("Expected table child, got %+v", block.Children)
+	}
+	if block.Children[0].Properties["used_columns"] != "id, name" {
+		t.Errorf("Unexpected table properties: %v", block.Children[0].Properties)
+	}
+}
+
+func TestParseSQLServerPlan(t *testing.T) {
+	document := `<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan">
+<BatchSequence><Batch><Statements>
+<StmtSimple StatementText="SELECT * FROM users" StatementType="SELECT">
+<QueryPlan>
+<RelOp PhysicalOp="Nested Loops" LogicalOp="Inner Join" EstimateRows="10">
+  <NestedLoops>
+    <RelOp PhysicalOp="Clustered Index Scan" LogicalOp="Clustered Index Scan" EstimateRows="10">
+      <IndexScan><Object Database="[db]" Schema="[dbo]" Table="[users]" Index="[PK_users]" /></IndexScan>
+    </RelOp>
+  </NestedLoops>
+</RelOp>
+</QueryPlan>
+</StmtSimple>
+</Statements></Batch></BatchSequence></ShowPlanXML>`
+
+	nodes, err := parseSQLServerPlan(strings.NewReader(document))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Operation != "Statement" {
+		t.Fatalf("Unexpected root: %+v", nodes)
+	}
+	join := nodes[0].Children[0]
+	if join.Operation != "Nested Loops" || join.Properties["LogicalOp"] != "Inner Join" {
+		t.Errorf("Unexpected join node: %+v", join)
+	}
+	scan := join.Children[0]
+	if scan.Properties["Object"] != "users" || scan.Properties["Index"] != "PK_users" {
+		t.Errorf("Unexpected scan properties: %v", scan.Properties)
+	}
+}
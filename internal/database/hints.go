@@ -0,0 +1,41 @@
+package database
+
+import (
+	"strings"
+)
+
+// Hints are per-statement options given in comments, such as
+// -- @max-rows 0. Names are stored lower-cased without the leading @.
+type Hints map[string]string
+
+// ParseHints extracts the hints from the comments of a statement. Each hint
+// is a comment starting with @name, optionally followed by a value.
+func ParseHints(statement string) Hints {
+	hints := Hints{}
+	for _, tok := range Tokenize(statement) {
+		if tok.Type != TokenComment {
+			continue
+		}
+
+		text := tok.Value
+		if strings.HasPrefix(text, "--") {
+			text = text[2:]
+		} else {
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "@") || len(fields[0]) == 1 {
+			continue
+		}
+
+		name := strings.ToLower(fields[0][1:])
+		hints[name] = strings.Join(fields[1:], " ")
+	}
+	return hints
+}
+
+// Has reports whether the hint is present
+func (h Hints) Has(name string) bool {
+	_, ok := h[name]
+	return ok
+}
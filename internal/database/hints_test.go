@@ -0,0 +1,24 @@
+package database
+
+import "testing"
+
+func TestParseHints(t *testing.T) {
+	statement := `-- @max-rows 0
+/* @allow-destructive */
+-- an ordinary comment
+SELECT '-- @not-a-hint' FROM events`
+
+	hints := ParseHints(statement)
+	if len(hints) != 2 {
+		t.Fatalf("Expected 2 hints, got %v", hints)
+	}
+	if hints["max-rows"] != "0" {
+		t.Errorf("Expected max-rows hint 0, got %q", hints["max-rows"])
+	}
+	if !hints.Has("allow-destructive") {
+		t.Error("Expected allow-destructive hint")
+	}
+	if hints.Has("not-a-hint") {
+		t.Error("Expected hints inside strings to be ignored")
+	}
+}
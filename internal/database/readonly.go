@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// readOnlySessionStatements make a new session read-only, for drivers that
+// support it. SQLite is opened with mode=ro instead.
+var readOnlySessionStatements = map[string]string{
+	"postgres": "SET SESSION CHARACTERISTICS AS TRANSACTION READ ONLY",
+	"mysql":    "SET SESSION TRANSACTION READ ONLY",
+}
+
+// openReadOnly opens a database whose sessions are read-only where the
+// driver supports it
+func openReadOnly(driverName, connectionString string) (*sql.DB, error) {
+	if driverName == "sqlite3" {
+		return sql.Open(driverName, readOnlySQLiteDSN(connectionString))
+	}
+
+	statement, ok := readOnlySessionStatements[driverName]
+	if !ok {
+		return sql.Open(driverName, connectionString)
+	}
+
+	// Open once to reach the driver, then wrap its connector so every new
+	// session in the pool is made read-only before it is used
+	db, err := sql.Open(driverName, connectionString)
+	if err != nil {
+		return nil, err
+	}
+	driverContext, ok := db.Driver().(driver.DriverContext)
+	db.Close()
+	if !ok {
+		return nil, fmt.Errorf("driver %s does not support read-only sessions", driverName)
+	}
+	connector, err := driverContext.OpenConnector(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&sessionConnector{Connector: connector, statement: statement}), nil
+}
+
+// readOnlySQLiteDSN turns a SQLite connection string into a read-only URI
+func readOnlySQLiteDSN(dsn string) string {
+	// SQLite only reads URI parameters such as mode from file: names
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	path, query, _ := strings.Cut(dsn, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		params = url.Values{}
+	}
+	params.Set("mode", "ro")
+	return path + "?" + params.Encode()
+}
+
+// sessionConnector runs a statement on each new connection before the pool
+// hands it out
+type sessionConnector struct {
+	driver.Connector
+	statement string
+}
+
+// Connect opens a connection and runs the session statement on it
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := execSession(ctx, conn, c.statement); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to make session read-only: %w", err)
+	}
+	return conn, nil
+}
+
+// execSession runs a statement without arguments on a driver connection
+func execSession(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// isReadOnlyStatement reports whether every statement of a batch is a query
+// that does not write, rejecting DML with RETURNING and SELECT ... INTO. The
+// statement wrapped by EXPLAIN is checked instead of the EXPLAIN itself,
+// since EXPLAIN ANALYZE runs it.
+func isReadOnlyStatement(statement, driver string) bool {
+	for _, part := range statementParts(statement, driver) {
+		if explained, ok := explainedStatement(part); ok {
+			part = explained
+		}
+		if classifyTokens(part) != StatementQuery || writesData(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// explainOptions are the words that may come between EXPLAIN and the
+// explained statement, such as ANALYZE and VERBOSE in PostgreSQL, EXTENDED
+// in MySQL and QUERY PLAN in SQLite
+var explainOptions = map[string]bool{
+	"ANALYZE":    true,
+	"ANALYSE":    true,
+	"VERBOSE":    true,
+	"EXTENDED":   true,
+	"PARTITIONS": true,
+	"QUERY":      true,
+	"PLAN":       true,
+}
+
+// explainedStatement returns the statement wrapped by an EXPLAIN, skipping
+// its options: words such as ANALYZE, a parenthesized option list as in
+// EXPLAIN (ANALYZE, FORMAT JSON) and FORMAT=JSON
+func explainedStatement(tokens []Token) ([]Token, bool) {
+	if len(tokens) == 0 || tokens[0].Keyword() != "EXPLAIN" {
+		return nil, false
+	}
+
+	tokens = tokens[1:]
+	for len(tokens) > 0 {
+		keyword := tokens[0].Keyword()
+		switch {
+		case tokens[0].IsPunct("(") && !startsQuery(tokens[1:]):
+			tokens = skipParentheses(tokens)
+		case explainOptions[keyword]:
+			tokens = tokens[1:]
+		case keyword == "FORMAT":
+			tokens = tokens[1:]
+			if len(tokens) > 0 && tokens[0].IsPunct("=") {
+				tokens = tokens[1:]
+			}
+			if len(tokens) > 0 {
+				tokens = tokens[1:]
+			}
+		default:
+			return tokens, true
+		}
+	}
+	return tokens, true
+}
+
+// startsQuery reports whether tokens start with a query, as in the first
+// part of (SELECT ...) UNION (SELECT ...)
+func startsQuery(tokens []Token) bool {
+	for len(tokens) > 0 && tokens[0].IsPunct("(") {
+		tokens = tokens[1:]
+	}
+	return len(tokens) > 0 && (queryKeywords[tokens[0].Keyword()] || tokens[0].Keyword() == "WITH")
+}
+
+// skipParentheses returns the tokens after the parenthesized group that
+// tokens start with
+func skipParentheses(tokens []Token) []Token {
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.IsPunct("("):
+			depth++
+		case tok.IsPunct(")"):
+			depth--
+			if depth == 0 {
+				return tokens[i+1:]
+			}
+		}
+	}
+	return nil
+}
+
+// writesData reports whether a query-like statement also writes data
+func writesData(tokens []Token) bool {
+	for len(tokens) > 0 && tokens[0].IsPunct("(") {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return false
+	}
+
+	switch keyword := tokens[0].Keyword(); {
+	case dmlKeywords[keyword]:
+		return true
+	case keyword == "WITH":
+		// Common table expressions may modify data themselves, as in
+		// WITH gone AS (DELETE ... RETURNING id) SELECT ...
+		for _, tok := range tokens {
+			if dmlKeywords[tok.Keyword()] && tok.Keyword() != "REPLACE" {
+				return true
+			}
+		}
+		return hasTopLevelKeyword(tokens, "INTO")
+	case keyword == "SELECT":
+		return hasTopLevelKeyword(tokens, "INTO")
+	}
+	return false
+}
+
+// checkReadOnly refuses statements other than queries on a read-only connection
+func (e *Executor) checkReadOnly(statement string) error {
+	if !e.connection.ReadOnly || isReadOnlyStatement(statement, e.connection.Driver) {
+		return nil
+	}
+	return fmt.Errorf("connection '%s' is read-only; refusing to run a statement that is not a query", e.connection.Name)
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/config"
+)
+
+func TestReadOnlySQLiteDSN(t *testing.T) {
+	tests := map[string]string{
+		"test.db":                    "file:test.db?mode=ro",
+		"file:test.db?cache=shared":  "file:test.db?cache=shared&mode=ro",
+		"file:test.db?mode=rwc":      "file:test.db?mode=ro",
+		"test.db?_busy_timeout=5000": "file:test.db?_busy_timeout=5000&mode=ro",
+	}
+	for dsn, want := range tests {
+		if got := readOnlySQLiteDSN(dsn); got != want {
+			t.Errorf("readOnlySQLiteDSN(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
+
+func TestIsReadOnlyStatement(t *testing.T) {
+	tests := []struct {
+		statement string
+		readOnly  bool
+	}{
+		{"SELECT * FROM users", true},
+		{"WITH recent AS (SELECT * FROM users) SELECT * FROM recent", true},
+		{"SHOW TABLES", true},
+		{"INSERT INTO users VALUES (1)", false},
+		{"DELETE FROM users WHERE id = 1 RETURNING id", false},
+		{"WITH gone AS (DELETE FROM users RETURNING id) SELECT * FROM gone", false},
+		{"WITH x AS (SELECT replace(name, 1, 2) FROM users) SELECT * FROM x", true},
+		{"WITH x AS (SELECT 1) INSERT INTO users SELECT * FROM x", false},
+		{"SELECT * INTO backup FROM users", false},
+		{"CREATE TABLE t (id INTEGER)", false},
+		{"SELECT 1; DROP TABLE users", false},
+		{"EXPLAIN SELECT * FROM users", true},
+		{"EXPLAIN QUERY PLAN SELECT * FROM users", true},
+		{"EXPLAIN ANALYZE DELETE FROM users", false},
+		{"EXPLAIN (ANALYZE, FORMAT JSON) UPDATE users SET name = 'x'", false},
+		{"EXPLAIN FORMAT=JSON INSERT INTO users VALUES (1)", false},
+		{"EXPLAIN ANALYZE VERBOSE WITH x AS (SELECT 1) INSERT INTO users SELECT * FROM x", false},
+		{"EXPLAIN (SELECT 1) UNION (SELECT 2)", true},
+	}
+
+	for _, tt := range tests {
+		if got := isReadOnlyStatement(tt.statement, ""); got != tt.readOnly {
+			t.Errorf("isReadOnlyStatement(%q) = %v, want %v", tt.statement, got, tt.readOnly)
+		}
+	}
+}
+
+func TestReadOnlyConnection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readonly.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER); INSERT INTO users VALUES (1)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	db.Close()
+
+	manager := NewManager()
+	defer manager.CloseAll()
+	if err := manager.Connect("replica", config.Connection{Driver: "sqlite3", ConnectionString: path, ReadOnly: true}); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	conn, _ := manager.GetConnection("replica")
+	executor := NewExecutor(conn)
+
+	result := executor.Execute("SELECT count(*) FROM users", 1, "test.sql")
+	if result.Error != nil || len(result.Rows) != 1 {
+		t.Errorf("Expected queries to run, got %v", result.Error)
+	}
+
+	result = executor.Execute("INSERT INTO users VALUES (2)", 2, "test.sql")
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "read-only") {
+		t.Errorf("Expected the executor to refuse the INSERT, got %v", result.Error)
+	}
+
+	// The session itself is read-only as a second layer of defense
+	if _, err := conn.DB.Exec("INSERT INTO users VALUES (2)"); err == nil {
+		t.Error("Expected the read-only session to reject the INSERT")
+	}
+}
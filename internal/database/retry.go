@@ -0,0 +1,206 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gosqlpp/internal/config"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// RetryPolicy controls automatic retries of statements that fail with
+// transient errors
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for each further retry
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+	// TransientCodes replaces the driver's default transient error codes
+	TransientCodes []string
+}
+
+// defaultTransientCodes are the error codes treated as transient per driver
+var defaultTransientCodes = map[string][]string{
+	// Serialization failure, deadlock, connection failures, admin shutdown
+	"postgres": {"40001", "40P01", "08000", "08001", "08003", "08004", "08006", "57P01"},
+	// Deadlock, lock wait timeout, server gone away, lost connection
+	"mysql": {"1213", "1205", "2006", "2013"},
+	// SQLITE_BUSY and SQLITE_LOCKED
+	"sqlite3": {"5", "6"},
+	// Deadlock victim, lock request timeout and Azure SQL transient errors
+	"sqlserver": {"1205", "1222", "4060", "40197", "40501", "40613", "49918", "49919", "49920"},
+}
+
+// NewRetryPolicy creates a retry policy from connection settings, filling in
+// defaults for settings left unset
+func NewRetryPolicy(cfg *config.RetryConfig) (*RetryPolicy, error) {
+	backoff, err := cfg.BackoffDuration()
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := cfg.MaxBackoffDuration()
+	if err != nil {
+		return nil, err
+	}
+	return &RetryPolicy{
+		MaxAttempts:    cfg.Attempts(),
+		Backoff:        backoff,
+		MaxBackoff:     maxBackoff,
+		TransientCodes: cfg.TransientCodes,
+	}, nil
+}
+
+// Delay returns how long to wait before the given retry (1 for the first retry)
+func (p *RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// IsTransient reports whether an error from the given driver is worth retrying
+func (p *RetryPolicy) IsTransient(driverName string, err error) bool {
+	if err == nil {
+		return false
+	}
+	if isConnectionReset(err) {
+		return true
+	}
+
+	code, ok := ErrorCode(err)
+	if !ok {
+		return false
+	}
+	codes := p.TransientCodes
+	if len(codes) == 0 {
+		codes = defaultTransientCodes[driverName]
+	}
+	for _, transient := range codes {
+		if strings.EqualFold(strings.TrimSpace(transient), code) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCode extracts the driver-specific error code from an error: the
+// SQLSTATE for PostgreSQL and the error number for the other drivers
+func ErrorCode(err error) (string, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code), true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return strconv.Itoa(int(mysqlErr.Number)), true
+	}
+	if code, ok := sqliteErrorCode(err); ok {
+		return code, true
+	}
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		return strconv.Itoa(int(mssqlErr.Number)), true
+	}
+	return "", false
+}
+
+// isConnectionReset reports whether an error means the connection was lost
+func isConnectionReset(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "connection reset") || strings.Contains(message, "broken pipe")
+}
+
+// SetRetryPolicy enables retrying statements that fail with transient errors.
+// A nil policy disables retries.
+func (e *Executor) SetRetryPolicy(policy *RetryPolicy) {
+	e.retry = policy
+}
+
+// SetLogWriter sets where retries are logged
+func (e *Executor) SetLogWriter(w io.Writer) {
+	e.logWriter = w
+}
+
+// runWithRetry runs a statement, retrying it after transient errors. A
+// statement is never retried inside an explicit transaction, because the
+// earlier statements of the transaction may have been rolled back with it,
+// and a batch of several statements is never retried as a whole, because
+// the statements before the failing one may already have been applied.
+// Batches that are split up, as for SQLite, retry each statement instead.
+func (e *Executor) runWithRetry(statement string, result *ExecutionResult) {
+	base := *result
+	for attempt := 1; ; attempt++ {
+		*result = base
+		e.run(statement, result)
+		result.Attempts = max(result.Attempts, attempt)
+
+		if !e.shouldRetry(statement, result.Error, attempt) {
+			break
+		}
+		delay := e.retry.Delay(attempt)
+		fmt.Fprintf(e.logWriter, "%s:%d: transient error (attempt %d of %d), retrying in %s: %v\n",
+			result.FileName, result.LineNumber, attempt, e.retry.MaxAttempts, delay, result.Error)
+		e.sleep(delay)
+	}
+
+	if result.Error == nil {
+		e.trackTransaction(statement)
+	}
+}
+
+// shouldRetry decides whether a failed attempt is retried
+func (e *Executor) shouldRetry(statement string, err error, attempt int) bool {
+	if err == nil || e.retry == nil || attempt >= e.retry.MaxAttempts {
+		return false
+	}
+	if e.inTransaction || hasTransactionControl(statement, e.connection.Driver) || isBatch(statement, e.connection.Driver) {
+		return false
+	}
+	return e.retry.IsTransient(e.connection.Driver, err)
+}
+
+// isBatch reports whether a statement consists of several statements
+func isBatch(statement, driver string) bool {
+	return len(executedStatements(statement, driver)) > 1
+}
+
+// trackTransaction records whether a successful statement left an explicit
+// transaction open
+func (e *Executor) trackTransaction(statement string) {
+	for _, part := range executedStatements(statement, e.connection.Driver) {
+		if !isTransactionControl(part) {
+			continue
+		}
+		switch part[0].Keyword() {
+		case "BEGIN", "START":
+			e.inTransaction = true
+		case "COMMIT", "END":
+			e.inTransaction = false
+		case "ROLLBACK":
+			// ROLLBACK TO SAVEPOINT keeps the transaction open
+			if !hasTopLevelKeyword(part, "TO") {
+				e.inTransaction = false
+			}
+		}
+	}
+}
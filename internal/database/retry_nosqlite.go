@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package database
+
+// sqliteErrorCode reports no code, as the SQLite driver needs cgo
+func sqliteErrorCode(err error) (string, bool) {
+	return "", false
+}
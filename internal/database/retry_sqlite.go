@@ -0,0 +1,19 @@
+//go:build cgo
+
+package database
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteErrorCode extracts the result code of a SQLite error
+func sqliteErrorCode(err error) (string, bool) {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return strconv.Itoa(int(sqliteErr.Code)), true
+	}
+	return "", false
+}
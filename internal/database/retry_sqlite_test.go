@@ -0,0 +1,27 @@
+//go:build cgo
+
+package database
+
+import (
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestRetryPolicyIsTransientSQLite(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+
+	if !policy.IsTransient("sqlite3", sqlite3.Error{Code: sqlite3.ErrBusy}) {
+		t.Error("Expected a busy database to be transient")
+	}
+	if !policy.IsTransient("sqlite3", sqlite3.Error{Code: sqlite3.ErrLocked}) {
+		t.Error("Expected a locked table to be transient")
+	}
+	if policy.IsTransient("sqlite3", sqlite3.Error{Code: sqlite3.ErrConstraint}) {
+		t.Error("Expected a constraint violation not to be transient")
+	}
+
+	if code, ok := ErrorCode(sqlite3.Error{Code: sqlite3.ErrBusy}); !ok || code != "5" {
+		t.Errorf("ErrorCode() = %q, %v, want \"5\", true", code, ok)
+	}
+}
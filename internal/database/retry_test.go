@@ -0,0 +1,220 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gosqlpp/internal/config"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 5, Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.Delay(i + 1); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyIsTransient(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+
+	tests := []struct {
+		name      string
+		driver    string
+		err       error
+		transient bool
+	}{
+		{"postgres serialization failure", "postgres", &pq.Error{Code: "40001"}, true},
+		{"postgres unique violation", "postgres", &pq.Error{Code: "23505"}, false},
+		{"mysql deadlock", "mysql", &mysql.MySQLError{Number: 1213}, true},
+		{"mysql syntax error", "mysql", &mysql.MySQLError{Number: 1064}, false},
+		{"connection reset", "postgres", errors.New("read tcp: connection reset by peer"), true},
+		{"plain error", "postgres", errors.New("relation does not exist"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.IsTransient(tt.driver, tt.err); got != tt.transient {
+				t.Errorf("IsTransient() = %v, want %v", got, tt.transient)
+			}
+		})
+	}
+
+	// Configured codes replace the defaults
+	custom := &RetryPolicy{MaxAttempts: 3, TransientCodes: []string{"1064"}}
+	if !custom.IsTransient("mysql", &mysql.MySQLError{Number: 1064}) {
+		t.Error("Expected configured code to be transient")
+	}
+	if custom.IsTransient("mysql", &mysql.MySQLError{Number: 1213}) {
+		t.Error("Expected default codes to be replaced by configured codes")
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	policy, err := NewRetryPolicy(&config.RetryConfig{Backoff: "50ms"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if policy.MaxAttempts != config.DefaultRetryAttempts || policy.Backoff != 50*time.Millisecond ||
+		policy.MaxBackoff != config.DefaultRetryMaxBackoff {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+}
+
+// newLockedDatabase creates a file database with a table and a second
+// connection holding its write lock
+func newLockedDatabase(t *testing.T) (*Executor, *sql.Tx) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "retry.db")
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=0")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	locker, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { locker.Close() })
+	tx, err := locker.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO items VALUES (0)"); err != nil {
+		t.Fatalf("Failed to take the write lock: %v", err)
+	}
+
+	return NewExecutor(&Connection{Name: "test", Driver: "sqlite3", DB: db}), tx
+}
+
+func TestExecuteRetriesTransientErrors(t *testing.T) {
+	executor, lock := newLockedDatabase(t)
+	var log bytes.Buffer
+	executor.SetLogWriter(&log)
+	executor.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+
+	// The lock is released while the executor waits before its first retry
+	var delays []time.Duration
+	executor.sleep = func(d time.Duration) {
+		delays = append(delays, d)
+		lock.Rollback()
+	}
+
+	result := executor.Execute("INSERT INTO items VALUES (1)", 7, "test.sql")
+	if result.Error != nil {
+		t.Fatalf("Expected retry to succeed, got %v", result.Error)
+	}
+	if result.Attempts != 2 || len(delays) != 1 {
+		t.Errorf("Expected 2 attempts and 1 delay, got %d attempts and %v", result.Attempts, delays)
+	}
+	if !strings.Contains(log.String(), "test.sql:7: transient error (attempt 1 of 3), retrying in 1ms") {
+		t.Errorf("Expected retry to be logged, got: %s", log.String())
+	}
+}
+
+func TestExecuteGivesUpAfterMaxAttempts(t *testing.T) {
+	executor, lock := newLockedDatabase(t)
+	defer lock.Rollback()
+	var log bytes.Buffer
+	executor.SetLogWriter(&log)
+	executor.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3})
+	executor.sleep = func(time.Duration) {}
+
+	result := executor.Execute("INSERT INTO items VALUES (1)", 1, "test.sql")
+	if result.Error == nil {
+		t.Fatal("Expected the statement to fail while the table is locked")
+	}
+	if result.Attempts != 3 || strings.Count(log.String(), "retrying") != 2 {
+		t.Errorf("Expected 3 attempts and 2 logged retries, got %d attempts: %s", result.Attempts, log.String())
+	}
+}
+
+func TestExecuteDoesNotRetryInTransaction(t *testing.T) {
+	executor, lock := newLockedDatabase(t)
+	defer lock.Rollback()
+	var log bytes.Buffer
+	executor.SetLogWriter(&log)
+	executor.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3})
+	executor.sleep = func(time.Duration) {}
+
+	if result := executor.Execute("BEGIN", 1, "test.sql"); result.Error != nil {
+		t.Fatalf("Failed to begin transaction: %v", result.Error)
+	}
+	result := executor.Execute("INSERT INTO items VALUES (1)", 2, "test.sql")
+	if result.Error == nil || result.Attempts != 1 {
+		t.Errorf("Expected a single failed attempt inside a transaction, got %d attempts (%v)", result.Attempts, result.Error)
+	}
+	if log.Len() != 0 {
+		t.Errorf("Expected no retries to be logged, got: %s", log.String())
+	}
+
+	executor.Execute("ROLLBACK", 3, "test.sql")
+	if executor.inTransaction {
+		t.Error("Expected ROLLBACK to end the transaction")
+	}
+}
+
+func TestExecuteDoesNotRetryBatches(t *testing.T) {
+	executor, lock := newLockedDatabase(t)
+	var log bytes.Buffer
+	executor.SetLogWriter(&log)
+	executor.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3})
+	executor.sleep = func(time.Duration) { lock.Rollback() }
+
+	// Temporary tables are written without the database's write lock
+	if result := executor.Execute("CREATE TEMP TABLE seen (n INTEGER)", 1, "test.sql"); result.Error != nil {
+		t.Fatalf("Failed to create table: %v", result.Error)
+	}
+
+	result := executor.Execute("INSERT INTO seen VALUES (1);\nINSERT INTO items VALUES (1)", 2, "test.sql")
+	if result.Error == nil || result.Attempts != 1 {
+		t.Errorf("Expected a single failed attempt for the batch, got %d attempts (%v)", result.Attempts, result.Error)
+	}
+
+	var count int
+	if err := executor.connection.DB.QueryRow("SELECT count(*) FROM seen").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the first statement of the batch to run once, got %d rows", count)
+	}
+}
+
+func TestExecuteRetriesSplitBatchStatements(t *testing.T) {
+	executor, lock := newLockedDatabase(t)
+	var log bytes.Buffer
+	executor.SetLogWriter(&log)
+	executor.SetRetryPolicy(&RetryPolicy{MaxAttempts: 3})
+	executor.sleep = func(time.Duration) { lock.Rollback() }
+
+	if result := executor.Execute("CREATE TEMP TABLE seen (n INTEGER)", 1, "test.sql"); result.Error != nil {
+		t.Fatalf("Failed to create table: %v", result.Error)
+	}
+
+	// A batch mixing statements and queries is split up, and only the
+	// failing statement is retried
+	result := executor.Execute("INSERT INTO seen VALUES (1);\nINSERT INTO items VALUES (1);\nSELECT count(*) FROM seen", 2, "test.sql")
+	if result.Error != nil || result.Attempts != 2 {
+		t.Errorf("Expected the failing statement to be retried once, got %d attempts (%v)", result.Attempts, result.Error)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][0] != int64(1) {
+		t.Errorf("Expected the first statement of the batch to run once, got %v", result.Rows)
+	}
+}
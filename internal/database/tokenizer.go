@@ -0,0 +1,412 @@
+package database
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TokenType identifies the kind of a SQL token
+type TokenType int
+
+const (
+	// TokenWord is a keyword or unquoted identifier
+	TokenWord TokenType = iota
+	// TokenQuotedIdent is an identifier quoted with "", `` or []
+	TokenQuotedIdent
+	// TokenString is a string literal, including postgres dollar-quoted strings
+	TokenString
+	// TokenNumber is a numeric literal
+	TokenNumber
+	// TokenComment is a -- line comment or /* */ block comment, or a # line
+	// comment in MySQL
+	TokenComment
+	// TokenPunct is an operator or punctuation character
+	TokenPunct
+)
+
+// Token is a single lexical element of a SQL statement
+type Token struct {
+	Type  TokenType
+	Value string
+	// Line is the 1-based line within the tokenized text where the token starts
+	Line int
+	// Offset is the rune offset of the token within the tokenized text
+	Offset int
+}
+
+// Keyword returns the upper-cased value of a word token, or "" for other tokens
+func (t Token) Keyword() string {
+	if t.Type != TokenWord {
+		return ""
+	}
+	return strings.ToUpper(t.Value)
+}
+
+// IsPunct reports whether the token is the given punctuation
+func (t Token) IsPunct(value string) bool {
+	return t.Type == TokenPunct && t.Value == value
+}
+
+// Tokenize splits SQL text into tokens. Whitespace is dropped; comments are
+// kept so callers can read statement hints. Unterminated strings and comments
+// run to the end of the input rather than failing, since the database will
+// report the syntax error with better context.
+func Tokenize(sql string) []Token {
+	return TokenizeDialect(sql, "")
+}
+
+// TokenizeDialect splits SQL text into tokens like Tokenize, following the
+// lexical rules of a driver's dialect: in MySQL, a backslash escapes the
+// next character of a string and # starts a line comment.
+func TokenizeDialect(sql, driver string) []Token {
+	mysql := driver == "mysql"
+	var tokens []Token
+	runes := []rune(sql)
+	line := 1
+	i := 0
+
+	for i < len(runes) {
+		r := runes[i]
+		start := i
+		startLine := line
+
+		switch {
+		case r == '\n':
+			line++
+			i++
+			continue
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '-' && peek(runes, i+1) == '-', r == '#' && mysql:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, Token{Type: TokenComment, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		case r == '/' && peek(runes, i+1) == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && peek(runes, i+1) == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i = min(i+2, len(runes))
+			tokens = append(tokens, Token{Type: TokenComment, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		case r == '\'':
+			i, line = scanQuoted(runes, i, line, '\'', mysql)
+			tokens = append(tokens, Token{Type: TokenString, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		case r == '"' && mysql:
+			// Double quotes delimit strings in MySQL unless ANSI_QUOTES is set
+			i, line = scanQuoted(runes, i, line, '"', true)
+			tokens = append(tokens, Token{Type: TokenString, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		case r == '"' || r == '`':
+			i, line = scanQuoted(runes, i, line, r, false)
+			tokens = append(tokens, Token{Type: TokenQuotedIdent, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		case r == '[':
+			i, line = scanQuoted(runes, i, line, ']', false)
+			tokens = append(tokens, Token{Type: TokenQuotedIdent, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		case r == '$':
+			if end, newLine, ok := scanDollarQuoted(runes, i, line); ok {
+				i, line = end, newLine
+				tokens = append(tokens, Token{Type: TokenString, Value: string(runes[start:i]), Line: startLine, Offset: start})
+				continue
+			}
+		case unicode.IsDigit(r) || (r == '.' && unicode.IsDigit(peek(runes, i+1))):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' ||
+				runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, Token{Type: TokenNumber, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		case isWordStart(r):
+			for i < len(runes) && isWordPart(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, Token{Type: TokenWord, Value: string(runes[start:i]), Line: startLine, Offset: start})
+			continue
+		}
+
+		// Anything else is a single punctuation character
+		i++
+		tokens = append(tokens, Token{Type: TokenPunct, Value: string(r), Line: startLine, Offset: start})
+	}
+
+	return tokens
+}
+
+// scanQuoted scans a quoted section starting at runes[i] and ending with the
+// closing rune; a doubled closing rune is treated as an escaped quote, and
+// with backslash set so is any character following a backslash
+func scanQuoted(runes []rune, i, line int, closing rune, backslash bool) (int, int) {
+	i++
+	for i < len(runes) {
+		if runes[i] == '\n' {
+			line++
+		}
+		if backslash && runes[i] == '\\' && i+1 < len(runes) {
+			if runes[i+1] == '\n' {
+				line++
+			}
+			i += 2
+			continue
+		}
+		if runes[i] == closing {
+			if peek(runes, i+1) == closing {
+				i += 2
+				continue
+			}
+			return i + 1, line
+		}
+		i++
+	}
+	return i, line
+}
+
+// scanDollarQuoted scans a postgres dollar-quoted string such as $$...$$ or $tag$...$tag$
+func scanDollarQuoted(runes []rune, i, line int) (int, int, bool) {
+	// A digit after the dollar sign is a positional parameter such as $1
+	j := i + 1
+	if j < len(runes) && unicode.IsDigit(runes[j]) {
+		return i, line, false
+	}
+	for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+		j++
+	}
+	if j >= len(runes) || runes[j] != '$' {
+		return i, line, false
+	}
+	tag := string(runes[i : j+1])
+
+	rest := string(runes[j+1:])
+	end := strings.Index(rest, tag)
+	if end < 0 {
+		return len(runes), line + strings.Count(rest, "\n"), true
+	}
+	body := []rune(rest[:end])
+	return j + 1 + len(body) + len([]rune(tag)), line + strings.Count(rest[:end], "\n"), true
+}
+
+func peek(runes []rune, i int) rune {
+	if i < len(runes) {
+		return runes[i]
+	}
+	return 0
+}
+
+func isWordStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '@' || r == '#'
+}
+
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '@' || r == '#'
+}
+
+// SplitStatements splits SQL text into statements at top-level semicolons.
+// Semicolons inside strings, comments and parentheses do not split. Each
+// statement starts at its first token, so comments before a statement stay
+// with it, while parts holding only comments are dropped along with the
+// text up to their end.
+func SplitStatements(sql string) []string {
+	return splitStatements(sql, "")
+}
+
+// splitStatements splits SQL text into statements in a driver's dialect
+func splitStatements(sql, driver string) []string {
+	runes := []rune(sql)
+	var statements []string
+
+	for _, part := range splitTokenStatements(TokenizeDialect(sql, driver)) {
+		// A part made only of comments, such as a trailing comment after
+		// the last semicolon, is not a statement
+		if len(significantTokens(part)) == 0 {
+			continue
+		}
+		last := part[len(part)-1]
+		end := last.Offset + len([]rune(last.Value))
+		if text := strings.TrimSpace(string(runes[part[0].Offset:end])); text != "" {
+			statements = append(statements, text)
+		}
+	}
+
+	return statements
+}
+
+// statementParts tokenizes SQL text in a driver's dialect and splits it into
+// statements without comments
+func statementParts(sql, driver string) [][]Token {
+	return splitTokenStatements(significantTokens(TokenizeDialect(sql, driver)))
+}
+
+// executedStatements splits SQL text like statementParts and further splits
+// a BEGIN ... END block that runs with the batch, such as a T-SQL block, into
+// the statements inside it. The body of a CREATE or ALTER, such as a trigger
+// or procedure, stays whole since it only runs when called.
+func executedStatements(sql, driver string) [][]Token {
+	var statements [][]Token
+	for _, part := range statementParts(sql, driver) {
+		switch {
+		case isTransactionControl(part):
+			statements = append(statements, part)
+		case part[0].Keyword() == "CREATE" || part[0].Keyword() == "ALTER":
+			statements = append(statements, part)
+		default:
+			statements = append(statements, blockStatements(part)...)
+		}
+	}
+	return statements
+}
+
+// blockStatements splits a statement at the semicolons and the BEGIN and END
+// keywords of the blocks inside it, dropping those keywords
+func blockStatements(tokens []Token) [][]Token {
+	var statements [][]Token
+	var current []Token
+	flush := func() {
+		if len(current) > 0 {
+			statements = append(statements, current)
+			current = nil
+		}
+	}
+
+	depth := 0
+	cases := 0
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.IsPunct("("):
+			depth++
+		case tok.IsPunct(")"):
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case tok.IsPunct(";"):
+			flush()
+			continue
+		case tok.Keyword() == "CASE":
+			cases++
+		case tok.Keyword() == "END" && cases > 0:
+			cases--
+		case tok.Keyword() == "BEGIN" && opensBlock(tokens[i+1:], len(current) == 0),
+			tok.Keyword() == "END" && !endsTransaction(tokens[i+1:]):
+			flush()
+			// T-SQL names its TRY and CATCH blocks after BEGIN and END
+			if i+1 < len(tokens) && (tokens[i+1].Keyword() == "TRY" || tokens[i+1].Keyword() == "CATCH") {
+				i++
+			}
+			continue
+		}
+		current = append(current, tok)
+	}
+	flush()
+
+	return statements
+}
+
+// significantTokens returns the tokens with comments removed
+func significantTokens(tokens []Token) []Token {
+	result := make([]Token, 0, len(tokens))
+	for _, tok := range tokens {
+		if tok.Type != TokenComment {
+			result = append(result, tok)
+		}
+	}
+	return result
+}
+
+// splitTokenStatements splits tokens into statements at top-level semicolons.
+// Semicolons inside parentheses or inside a BEGIN ... END block, such as a
+// trigger or procedure body, do not end the statement.
+func splitTokenStatements(tokens []Token) [][]Token {
+	var statements [][]Token
+	depth := 0
+	blocks := 0
+	start := 0
+	leading := true
+
+	for i, tok := range tokens {
+		if tok.Type == TokenComment {
+			continue
+		}
+		switch {
+		case tok.IsPunct("("):
+			depth++
+		case tok.IsPunct(")"):
+			if depth > 0 {
+				depth--
+			}
+		case tok.Keyword() == "BEGIN" && opensBlock(tokens[i+1:], leading):
+			blocks++
+		case tok.Keyword() == "CASE":
+			blocks++
+		case tok.Keyword() == "END" && blocks > 0 && !closesUntrackedBlock(tokens[i+1:]):
+			blocks--
+		case tok.IsPunct(";") && depth == 0 && blocks == 0:
+			if i > start {
+				statements = append(statements, tokens[start:i])
+			}
+			start = i + 1
+			leading = true
+			continue
+		}
+		leading = false
+	}
+	if start < len(tokens) {
+		statements = append(statements, tokens[start:])
+	}
+
+	return statements
+}
+
+// opensBlock reports whether a BEGIN followed by rest opens a block rather
+// than a transaction. A transaction BEGIN leads its statement and stands
+// alone or is followed by a transaction keyword.
+func opensBlock(rest []Token, leading bool) bool {
+	if !leading {
+		return true
+	}
+	next, ok := nextSignificant(rest)
+	if !ok || next.IsPunct(";") {
+		return false
+	}
+	return !beginTransactionKeywords[next.Keyword()]
+}
+
+// closesUntrackedBlock reports whether an END followed by rest closes a
+// MySQL IF, LOOP, WHILE or REPEAT, whose openers are not counted as blocks
+func closesUntrackedBlock(rest []Token) bool {
+	next, ok := nextSignificant(rest)
+	if !ok {
+		return false
+	}
+	switch next.Keyword() {
+	case "IF", "LOOP", "WHILE", "REPEAT":
+		return true
+	}
+	return false
+}
+
+// endsTransaction reports whether an END followed by rest is END TRANSACTION
+// or END WORK
+func endsTransaction(rest []Token) bool {
+	next, ok := nextSignificant(rest)
+	return ok && (next.Keyword() == "TRANSACTION" || next.Keyword() == "WORK")
+}
+
+// nextSignificant returns the first token that is not a comment
+func nextSignificant(tokens []Token) (Token, bool) {
+	for _, tok := range tokens {
+		if tok.Type != TokenComment {
+			return tok, true
+		}
+	}
+	return Token{}, false
+}
@@ -0,0 +1,148 @@
+package database
+
+import "testing"
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("SELECT 'it''s', \"col\", $$body;$$ -- note\n/* c */ FROM t WHERE x = $1 AND y = 1.5e3;")
+
+	expected := []struct {
+		tokenType TokenType
+		value     string
+	}{
+		{TokenWord, "SELECT"},
+		{TokenString, "'it''s'"},
+		{TokenPunct, ","},
+		{TokenQuotedIdent, "\"col\""},
+		{TokenPunct, ","},
+		{TokenString, "$$body;$$"},
+		{TokenComment, "-- note"},
+		{TokenComment, "/* c */"},
+		{TokenWord, "FROM"},
+		{TokenWord, "t"},
+		{TokenWord, "WHERE"},
+		{TokenWord, "x"},
+		{TokenPunct, "="},
+		{TokenPunct, "$"},
+		{TokenNumber, "1"},
+		{TokenWord, "AND"},
+		{TokenWord, "y"},
+		{TokenPunct, "="},
+		{TokenNumber, "1.5e3"},
+		{TokenPunct, ";"},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i, exp := range expected {
+		if tokens[i].Type != exp.tokenType || tokens[i].Value != exp.value {
+			t.Errorf("Token %d = %v %q, expected %v %q", i, tokens[i].Type, tokens[i].Value, exp.tokenType, exp.value)
+		}
+	}
+
+	// Comments on later lines report their line number
+	if tokens[7].Line != 2 {
+		t.Errorf("Expected block comment on line 2, got %d", tokens[7].Line)
+	}
+}
+
+func TestSplitTokenStatements(t *testing.T) {
+	parts := splitTokenStatements(Tokenize("INSERT INTO t VALUES ('a;b'); SELECT (1); ;"))
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(parts))
+	}
+	if parts[1][0].Keyword() != "SELECT" {
+		t.Errorf("Expected second statement to start with SELECT, got %s", parts[1][0].Value)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := SplitStatements("-- first\nINSERT INTO t VALUES ('a;b');\nSELECT 1 ; -- trailing")
+	expected := []string{"-- first\nINSERT INTO t VALUES ('a;b')", "SELECT 1"}
+
+	if len(statements) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d: %q", len(expected), len(statements), statements)
+	}
+	for i := range expected {
+		if statements[i] != expected[i] {
+			t.Errorf("Statement %d = %q, expected %q", i, statements[i], expected[i])
+		}
+	}
+}
+
+func TestSplitStatementsSkipsCommentOnlyParts(t *testing.T) {
+	statements := SplitStatements("SELECT 1;\n-- note\n;;\nSELECT 2")
+	expected := []string{"SELECT 1", "SELECT 2"}
+
+	if len(statements) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d: %q", len(expected), len(statements), statements)
+	}
+	for i := range expected {
+		if statements[i] != expected[i] {
+			t.Errorf("Statement %d = %q, expected %q", i, statements[i], expected[i])
+		}
+	}
+}
+
+func TestSplitStatementsKeepsBlocks(t *testing.T) {
+	statements := SplitStatements("BEGIN;\nCREATE TRIGGER trg AFTER INSERT ON t\nBEGIN\n  UPDATE t SET x = CASE WHEN x IS NULL THEN 1 END;\n  DELETE FROM t WHERE x = 2;\nEND;\nCOMMIT")
+	expected := []string{
+		"BEGIN",
+		"CREATE TRIGGER trg AFTER INSERT ON t\nBEGIN\n  UPDATE t SET x = CASE WHEN x IS NULL THEN 1 END;\n  DELETE FROM t WHERE x = 2;\nEND",
+		"COMMIT",
+	}
+
+	if len(statements) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d: %q", len(expected), len(statements), statements)
+	}
+	for i := range expected {
+		if statements[i] != expected[i] {
+			t.Errorf("Statement %d = %q, expected %q", i, statements[i], expected[i])
+		}
+	}
+}
+
+func TestTokenizeMySQL(t *testing.T) {
+	tokens := TokenizeDialect("SELECT 'it\\'s; here', \"a\\\"b\" # note; here\nFROM t", "mysql")
+
+	expected := []struct {
+		tokenType TokenType
+		value     string
+	}{
+		{TokenWord, "SELECT"},
+		{TokenString, `'it\'s; here'`},
+		{TokenPunct, ","},
+		{TokenString, `"a\"b"`},
+		{TokenComment, "# note; here"},
+		{TokenWord, "FROM"},
+		{TokenWord, "t"},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i, exp := range expected {
+		if tokens[i].Type != exp.tokenType || tokens[i].Value != exp.value {
+			t.Errorf("Token %d = %v %q, expected %v %q", i, tokens[i].Type, tokens[i].Value, exp.tokenType, exp.value)
+		}
+	}
+	if tokens[5].Line != 2 {
+		t.Errorf("Expected FROM on line 2, got %d", tokens[5].Line)
+	}
+
+	// Other dialects keep backslashes literal and # in identifiers such as
+	// SQL Server temporary tables
+	if tokens := Tokenize(`SELECT 'a\' FROM #tmp`); len(tokens) != 4 || tokens[3].Value != "#tmp" {
+		t.Errorf("Unexpected standard tokens: %v", tokens)
+	}
+}
+
+func TestClassifyMySQLEscapes(t *testing.T) {
+	statement := "INSERT INTO t VALUES ('it\\'s; SELECT 1') # SELECT; here"
+	if parts := statementParts(statement, "mysql"); len(parts) != 1 {
+		t.Errorf("Expected a single MySQL statement, got %d", len(parts))
+	}
+	if kind := classifyStatement(statement, "mysql"); kind != StatementExec {
+		t.Errorf("Expected the MySQL statement to be classified as exec, got %v", kind)
+	}
+}
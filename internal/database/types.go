@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ColumnType describes a result column as reported by the driver. Fields the
+// driver cannot report are left nil.
+type ColumnType struct {
+	Name         string `json:"name" yaml:"name"`
+	DatabaseType string `json:"database_type" yaml:"database_type"`
+	Nullable     *bool  `json:"nullable" yaml:"nullable"`
+	Length       *int64 `json:"length,omitempty" yaml:"length,omitempty"`
+	Precision    *int64 `json:"precision,omitempty" yaml:"precision,omitempty"`
+	Scale        *int64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+}
+
+// Decimal is an exact numeric value kept in its textual form so it is never
+// rounded through a float
+type Decimal string
+
+// Binary is the value of a binary column such as BLOB, BYTEA or VARBINARY
+type Binary []byte
+
+// newColumnTypes converts the driver's column types to ColumnType values
+func newColumnTypes(types []*sql.ColumnType) []ColumnType {
+	result := make([]ColumnType, len(types))
+	for i, ct := range types {
+		col := ColumnType{
+			Name:         ct.Name(),
+			DatabaseType: ct.DatabaseTypeName(),
+		}
+		if nullable, ok := ct.Nullable(); ok {
+			col.Nullable = &nullable
+		}
+		if length, ok := ct.Length(); ok {
+			col.Length = &length
+		}
+		if precision, scale, ok := ct.DecimalSize(); ok {
+			col.Precision = &precision
+			col.Scale = &scale
+		}
+		result[i] = col
+	}
+	return result
+}
+
+// Type name groups used to interpret raw driver values. Names are the
+// upper-cased DatabaseTypeName values reported by the supported drivers.
+var (
+	binaryTypeNames = map[string]bool{
+		"BLOB": true, "TINYBLOB": true, "MEDIUMBLOB": true, "LONGBLOB": true,
+		"BYTEA": true, "BINARY": true, "VARBINARY": true, "IMAGE": true,
+	}
+	decimalTypeNames = map[string]bool{
+		"DECIMAL": true, "NUMERIC": true, "MONEY": true, "SMALLMONEY": true,
+	}
+	integerTypeNames = map[string]bool{
+		"INT": true, "INTEGER": true, "BIGINT": true, "SMALLINT": true, "TINYINT": true,
+		"MEDIUMINT": true, "INT2": true, "INT4": true, "INT8": true, "YEAR": true,
+	}
+	floatTypeNames = map[string]bool{
+		"FLOAT": true, "DOUBLE": true, "REAL": true, "FLOAT4": true, "FLOAT8": true,
+		"DOUBLE PRECISION": true,
+	}
+	boolTypeNames = map[string]bool{
+		"BOOL": true, "BOOLEAN": true, "BIT": true,
+	}
+	timeTypeNames = map[string]bool{
+		"DATE": true, "DATETIME": true, "TIMESTAMP": true,
+	}
+)
+
+// timeLayouts are the textual date/time formats drivers return when they do
+// not convert values to time.Time themselves (MySQL without parseTime=true).
+// Such text has no time zone, so it is read in the connection's location.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// normalizeValue converts a raw driver value to a Go value matching the
+// column's database type: exact decimals, integers, floats, booleans, times,
+// binary data or strings. Date/time text is read in loc, or in UTC when loc
+// is nil.
+func normalizeValue(val interface{}, col ColumnType, loc *time.Location) interface{} {
+	typeName := strings.TrimPrefix(strings.ToUpper(col.DatabaseType), "UNSIGNED ")
+
+	switch v := val.(type) {
+	case []byte:
+		text := string(v)
+		switch {
+		case binaryTypeNames[typeName]:
+			return Binary(v)
+		case decimalTypeNames[typeName]:
+			return Decimal(text)
+		case integerTypeNames[typeName]:
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return n
+			}
+			if n, err := strconv.ParseUint(text, 10, 64); err == nil {
+				return n
+			}
+		case floatTypeNames[typeName]:
+			if f, err := strconv.ParseFloat(text, 64); err == nil {
+				return f
+			}
+		case boolTypeNames[typeName]:
+			// MySQL returns BIT columns as raw bytes
+			if len(v) == 1 && (v[0] == 0 || v[0] == 1) {
+				return v[0] == 1
+			}
+			if b, err := strconv.ParseBool(text); err == nil {
+				return b
+			}
+		case timeTypeNames[typeName]:
+			if loc == nil {
+				loc = time.UTC
+			}
+			for _, layout := range timeLayouts {
+				if t, err := time.ParseInLocation(layout, text, loc); err == nil {
+					return t
+				}
+			}
+		}
+		// Bytes that are not valid text are binary data even without a
+		// declared binary type, such as SQLite blob expressions
+		if !utf8.Valid(v) {
+			return Binary(v)
+		}
+		return text
+	case int64:
+		// SQLite stores declared BOOLEAN columns as integers
+		if boolTypeNames[typeName] {
+			return v != 0
+		}
+	}
+
+	return val
+}
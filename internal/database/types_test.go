@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		dbType   string
+		expected interface{}
+	}{
+		{"decimal", []byte("12345.6789"), "DECIMAL", Decimal("12345.6789")},
+		{"numeric", []byte("0.10"), "NUMERIC", Decimal("0.10")},
+		{"integer", []byte("42"), "INT", int64(42)},
+		{"unsigned integer", []byte("18446744073709551615"), "UNSIGNED BIGINT", uint64(18446744073709551615)},
+		{"float", []byte("1.5"), "DOUBLE", 1.5},
+		{"mysql bit", []byte{1}, "BIT", true},
+		{"text bool", []byte("false"), "BOOLEAN", false},
+		{"sqlite boolean", int64(1), "BOOLEAN", true},
+		{"text", []byte("hello"), "VARCHAR", "hello"},
+		{"invalid integer stays text", []byte("abc"), "INT", "abc"},
+		{"untyped text", []byte("hello"), "", "hello"},
+		{"plain integer", int64(7), "INTEGER", int64(7)},
+		{"nil", nil, "INT", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := normalizeValue(tt.value, ColumnType{DatabaseType: tt.dbType}, nil)
+			if result != tt.expected {
+				t.Errorf("normalizeValue(%v, %s) = %#v, expected %#v", tt.value, tt.dbType, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeBinaryAndTimeValues(t *testing.T) {
+	if v, ok := normalizeValue([]byte{0x00, 0xff}, ColumnType{DatabaseType: "BLOB"}, nil).(Binary); !ok || len(v) != 2 {
+		t.Errorf("Expected BLOB bytes to become Binary, got %#v", v)
+	}
+
+	// Invalid UTF-8 without a declared type is treated as binary
+	if _, ok := normalizeValue([]byte{0xff, 0xfe}, ColumnType{}, nil).(Binary); !ok {
+		t.Error("Expected invalid UTF-8 bytes to become Binary")
+	}
+
+	value := normalizeValue([]byte("2024-03-01 12:30:45"), ColumnType{DatabaseType: "DATETIME"}, nil)
+	expected := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	if ts, ok := value.(time.Time); !ok || !ts.Equal(expected) {
+		t.Errorf("Expected %v, got %#v", expected, value)
+	}
+
+	// Text is read in the connection's location, such as MySQL's loc
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+	value = normalizeValue([]byte("2024-03-01 12:30:45"), ColumnType{DatabaseType: "DATETIME"}, paris)
+	expected = time.Date(2024, 3, 1, 12, 30, 45, 0, paris)
+	if ts, ok := value.(time.Time); !ok || !ts.Equal(expected) || ts.Location() != paris {
+		t.Errorf("Expected %v, got %#v", expected, value)
+	}
+}
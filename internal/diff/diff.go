@@ -0,0 +1,367 @@
+package diff
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gosqlpp/internal/database"
+	"gosqlpp/internal/file"
+)
+
+// Report holds the differences found between the results of two connections
+type Report struct {
+	Left       string          `json:"left"`
+	Right      string          `json:"right"`
+	Statements []StatementDiff `json:"statements"`
+}
+
+// StatementDiff compares the results of one statement on both connections
+type StatementDiff struct {
+	FileName   string    `json:"file"`
+	LineNumber int       `json:"line"`
+	Statement  string    `json:"statement"`
+	LeftError  string    `json:"left_error,omitempty"`
+	RightError string    `json:"right_error,omitempty"`
+	ResultSets []SetDiff `json:"result_sets,omitempty"`
+}
+
+// SetDiff compares one result set. Rows are matched on the Keys columns, or
+// by position when there are none. Result sets whose columns differ are not
+// compared row by row.
+type SetDiff struct {
+	Columns      []string        `json:"columns"`
+	Keys         []string        `json:"keys,omitempty"`
+	LeftColumns  []string        `json:"left_columns,omitempty"`
+	RightColumns []string        `json:"right_columns,omitempty"`
+	LeftRows     int             `json:"left_rows"`
+	RightRows    int             `json:"right_rows"`
+	Truncated    bool            `json:"truncated,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Removed      [][]interface{} `json:"-"`
+	Added        [][]interface{} `json:"-"`
+	Changed      []RowChange     `json:"-"`
+}
+
+// RowChange is a row found on both sides with different values in Columns
+type RowChange struct {
+	Left    []interface{}
+	Right   []interface{}
+	Columns []string
+}
+
+// Differ runs statements on two executors and compares their results
+type Differ struct {
+	left  *database.Executor
+	right *database.Executor
+	keys  []string
+}
+
+// NewDiffer creates a differ that matches rows on the key columns, or by
+// position when no keys are given
+func NewDiffer(left, right *database.Executor, keys []string) *Differ {
+	return &Differ{left: left, right: right, keys: keys}
+}
+
+// KeyHint is the hint that sets the key columns of a statement's rows,
+// separated by commas, instead of the keys given to NewDiffer
+const KeyHint = "diff-key"
+
+// DiffFile runs the statements of a SQL file on both connections and adds
+// their differences to the report. Schema and processor commands are
+// skipped.
+func (d *Differ) DiffFile(report *Report, filename string) error {
+	statements, err := file.LoadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range statements {
+		if strings.TrimSpace(stmt.SQL) == "" || stmt.IsCommand() {
+			continue
+		}
+		keys := d.keys
+		if hint := database.ParseHints(stmt.SQL)[KeyHint]; hint != "" {
+			keys = strings.Split(strings.ReplaceAll(hint, " ", ""), ",")
+		}
+		line, name := stmt.Location.OriginalLine, stmt.Location.OriginalFile
+		left := d.left.Execute(stmt.SQL, line, name)
+		right := d.right.Execute(stmt.SQL, line, name)
+		report.Statements = append(report.Statements, CompareResults(left, right, keys))
+	}
+	return nil
+}
+
+// CompareResults compares the results of a statement run on both
+// connections, result set by result set
+func CompareResults(left, right *database.ExecutionResult, keys []string) StatementDiff {
+	diff := StatementDiff{
+		FileName:   left.FileName,
+		LineNumber: left.LineNumber,
+		Statement:  left.Statement,
+	}
+	if left.Error != nil {
+		diff.LeftError = left.Error.Error()
+	}
+	if right.Error != nil {
+		diff.RightError = right.Error.Error()
+	}
+	if left.Error != nil || right.Error != nil {
+		return diff
+	}
+
+	leftSets, rightSets := left.GetResultSets(), right.GetResultSets()
+	for i := 0; i < max(len(leftSets), len(rightSets)); i++ {
+		var leftSet, rightSet database.ResultSet
+		if i < len(leftSets) {
+			leftSet = leftSets[i]
+		}
+		if i < len(rightSets) {
+			rightSet = rightSets[i]
+		}
+		diff.ResultSets = append(diff.ResultSets, CompareSets(leftSet, rightSet, keys))
+	}
+	return diff
+}
+
+// CompareSets compares two result sets, matching rows on the key columns,
+// or by position when there are none or the result sets lack one of them
+func CompareSets(left, right database.ResultSet, keys []string) SetDiff {
+	diff := SetDiff{
+		Columns:   left.Columns,
+		LeftRows:  len(left.Rows),
+		RightRows: len(right.Rows),
+		Truncated: left.Truncated || right.Truncated,
+	}
+	if !sameColumns(left.Columns, right.Columns) {
+		diff.LeftColumns = left.Columns
+		diff.RightColumns = right.Columns
+		return diff
+	}
+
+	keyIndexes, ok := columnIndexes(left.Columns, keys)
+	if !ok {
+		diff.compareByPosition(left.Rows, right.Rows)
+		return diff
+	}
+
+	diff.Keys = keys
+	if err := diff.compareByKey(left.Rows, right.Rows, keyIndexes); err != nil {
+		diff.Error = err.Error()
+	}
+	return diff
+}
+
+// Differences returns the number of differences in the statement: one for
+// each error, each result set with different columns and each added,
+// removed or changed row
+func (s StatementDiff) Differences() int {
+	count := 0
+	if s.LeftError != "" {
+		count++
+	}
+	if s.RightError != "" {
+		count++
+	}
+	for _, set := range s.ResultSets {
+		count += set.Differences()
+	}
+	return count
+}
+
+// Differences returns the number of differences in the result set
+func (s SetDiff) Differences() int {
+	if s.Error != "" || s.LeftColumns != nil || s.RightColumns != nil {
+		return 1
+	}
+	return len(s.Removed) + len(s.Added) + len(s.Changed)
+}
+
+// Differences returns the number of differences in the report
+func (r *Report) Differences() int {
+	count := 0
+	for _, stmt := range r.Statements {
+		count += stmt.Differences()
+	}
+	return count
+}
+
+// compareByPosition compares the rows at the same position on both sides
+func (s *SetDiff) compareByPosition(left, right [][]interface{}) {
+	for i := 0; i < max(len(left), len(right)); i++ {
+		switch {
+		case i >= len(right):
+			s.Removed = append(s.Removed, left[i])
+		case i >= len(left):
+			s.Added = append(s.Added, right[i])
+		default:
+			s.compareRows(left[i], right[i])
+		}
+	}
+}
+
+// compareByKey matches rows whose key columns hold the same values
+func (s *SetDiff) compareByKey(left, right [][]interface{}, keyIndexes []int) error {
+	rightByKey := make(map[string]int, len(right))
+	for i, row := range right {
+		key := rowKey(row, keyIndexes)
+		if _, ok := rightByKey[key]; ok {
+			return fmt.Errorf("duplicate key %s on the right", keyText(row, keyIndexes))
+		}
+		rightByKey[key] = i
+	}
+
+	matched := make(map[int]bool, len(right))
+	seen := make(map[string]bool, len(left))
+	for _, row := range left {
+		key := rowKey(row, keyIndexes)
+		if seen[key] {
+			return fmt.Errorf("duplicate key %s on the left", keyText(row, keyIndexes))
+		}
+		seen[key] = true
+
+		i, ok := rightByKey[key]
+		if !ok {
+			s.Removed = append(s.Removed, row)
+			continue
+		}
+		matched[i] = true
+		s.compareRows(row, right[i])
+	}
+	for i, row := range right {
+		if !matched[i] {
+			s.Added = append(s.Added, row)
+		}
+	}
+	return nil
+}
+
+// compareRows records a change when two matched rows differ
+func (s *SetDiff) compareRows(left, right []interface{}) {
+	var changed []string
+	for i, col := range s.Columns {
+		if !equalValues(rowValue(left, i), rowValue(right, i)) {
+			changed = append(changed, col)
+		}
+	}
+	if len(changed) > 0 {
+		s.Changed = append(s.Changed, RowChange{Left: left, Right: right, Columns: changed})
+	}
+}
+
+// sameColumns reports whether two result sets have the same columns in the
+// same order, ignoring case since databases differ in how they fold names
+func sameColumns(left, right []string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if !strings.EqualFold(left[i], right[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnIndexes returns the positions of the key columns, or false when
+// there are no keys or a key column is missing
+func columnIndexes(columns, keys []string) ([]int, bool) {
+	if len(keys) == 0 {
+		return nil, false
+	}
+	indexes := make([]int, len(keys))
+	for i, key := range keys {
+		indexes[i] = -1
+		for j, col := range columns {
+			if strings.EqualFold(col, key) {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return nil, false
+		}
+	}
+	return indexes, true
+}
+
+// rowKey builds the map key of a row's key values. Each value is tagged as
+// NULL or prefixed with the length of its text, so that NULL and the text
+// NULL, or ("a, b") and ("a", "b"), do not collide.
+func rowKey(row []interface{}, keyIndexes []int) string {
+	var b strings.Builder
+	for _, index := range keyIndexes {
+		val := rowValue(row, index)
+		if val == nil {
+			b.WriteString("N;")
+			continue
+		}
+		text := ValueText(val)
+		fmt.Fprintf(&b, "V%d:%s", len(text), text)
+	}
+	return b.String()
+}
+
+// keyText joins the text of a row's key values for messages
+func keyText(row []interface{}, keyIndexes []int) string {
+	parts := make([]string, len(keyIndexes))
+	for i, index := range keyIndexes {
+		parts[i] = ValueText(rowValue(row, index))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// rowValue returns the value in column i of a row, or nil when the row is
+// shorter
+func rowValue(row []interface{}, i int) interface{} {
+	if i < len(row) {
+		return row[i]
+	}
+	return nil
+}
+
+// equalValues reports whether two values are the same data. Values are
+// compared by their text so that the same number read as different types
+// by different drivers, such as an integer and a decimal, is equal.
+func equalValues(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	return ValueText(left) == ValueText(right)
+}
+
+// ValueText returns the text a value is compared and shown as: numbers
+// without exponents or trailing zeros, times in UTC and binary data in hex
+func ValueText(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case database.Binary:
+		return "0x" + hex.EncodeToString(v)
+	case database.Decimal:
+		return trimDecimal(string(v))
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// trimDecimal removes the trailing zeros of a decimal's fraction
+func trimDecimal(s string) string {
+	if !strings.Contains(s, ".") || strings.ContainsAny(s, "eE") {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,247 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/config"
+	"gosqlpp/internal/database"
+	"gosqlpp/internal/output"
+)
+
+// newTestExecutor opens an in-memory sqlite database and runs the setup
+// statements on it
+func newTestExecutor(t *testing.T, manager *database.Manager, name string, setup ...string) *database.Executor {
+	t.Helper()
+
+	connConfig := config.Connection{
+		Driver:           "sqlite3",
+		ConnectionString: ":memory:",
+	}
+	if err := manager.Connect(name, connConfig); err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	conn, err := manager.GetConnection(name)
+	if err != nil {
+		t.Fatalf("Failed to get test connection: %v", err)
+	}
+	// Keep a single connection so the in-memory database survives between statements
+	conn.DB.SetMaxOpenConns(1)
+
+	executor := database.NewExecutor(conn)
+	for _, stmt := range setup {
+		if result := executor.Execute(stmt, 1, "setup"); result.Error != nil {
+			t.Fatalf("Setup statement failed: %v", result.Error)
+		}
+	}
+	return executor
+}
+
+// newTestDiffer creates a differ over two databases holding the given rows
+// of a users table
+func newTestDiffer(t *testing.T, keys []string, leftRows, rightRows string) *Differ {
+	t.Helper()
+
+	manager := database.NewManager()
+	t.Cleanup(func() { manager.CloseAll() })
+
+	create := "CREATE TABLE users (id INTEGER, name TEXT, score REAL)"
+	left := newTestExecutor(t, manager, "left", create, "INSERT INTO users VALUES "+leftRows)
+	right := newTestExecutor(t, manager, "right", create, "INSERT INTO users VALUES "+rightRows)
+	return NewDiffer(left, right, keys)
+}
+
+// diffScript writes a SQL file and diffs it
+func diffScript(t *testing.T, differ *Differ, script string) *Report {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "check.sql")
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	report := &Report{Left: "left", Right: "right"}
+	if err := differ.DiffFile(report, path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return report
+}
+
+func TestDiffByKey(t *testing.T) {
+	differ := newTestDiffer(t, []string{"id"},
+		"(1, 'ann', 1.5), (2, 'bob', 2), (3, 'cy', 3)",
+		"(2, 'robert', 2), (1, 'ann', 1.5), (4, 'dee', 4)")
+	report := diffScript(t, differ, "SELECT * FROM users\ngo\nSELECT COUNT(*) AS n FROM users\n")
+
+	if len(report.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(report.Statements))
+	}
+	set := report.Statements[0].ResultSets[0]
+	if len(set.Removed) != 1 || set.Removed[0][0] != int64(3) {
+		t.Errorf("Expected row 3 to be removed, got %v", set.Removed)
+	}
+	if len(set.Added) != 1 || set.Added[0][0] != int64(4) {
+		t.Errorf("Expected row 4 to be added, got %v", set.Added)
+	}
+	if len(set.Changed) != 1 || strings.Join(set.Changed[0].Columns, ",") != "name" {
+		t.Errorf("Expected the name of row 2 to change, got %v", set.Changed)
+	}
+
+	// The count has no id column and is compared by position
+	if count := report.Statements[1]; count.Differences() != 0 || count.ResultSets[0].Keys != nil {
+		t.Errorf("Expected identical counts compared by position, got %+v", count)
+	}
+	if report.Differences() != 3 {
+		t.Errorf("Expected 3 differences, got %d", report.Differences())
+	}
+}
+
+func TestDiffByPosition(t *testing.T) {
+	differ := newTestDiffer(t, nil,
+		"(1, 'ann', 1.5), (2, 'bob', 2)",
+		"(1, 'ann', 1.5), (2, 'bob', 2), (3, 'cy', 3)")
+	report := diffScript(t, differ, "SELECT * FROM users ORDER BY id")
+
+	set := report.Statements[0].ResultSets[0]
+	if len(set.Added) != 1 || len(set.Removed) != 0 || len(set.Changed) != 0 {
+		t.Errorf("Expected one added row, got %+v", set)
+	}
+}
+
+func TestDiffKeyHint(t *testing.T) {
+	differ := newTestDiffer(t, nil,
+		"(1, 'ann', 1.5), (2, 'bob', 2)",
+		"(2, 'bob', 2), (1, 'ann', 1.5)")
+	report := diffScript(t, differ, "-- @diff-key id\nSELECT * FROM users")
+
+	if report.Differences() != 0 {
+		t.Errorf("Expected rows matched on id to be identical, got %d differences", report.Differences())
+	}
+}
+
+func TestDiffErrorsAndColumns(t *testing.T) {
+	differ := newTestDiffer(t, nil, "(1, 'ann', 1.5)", "(1, 'ann', 1.5)")
+	report := diffScript(t, differ, "SELECT * FROM missing\ngo\nSELECT id, name FROM users\n")
+
+	if stmt := report.Statements[0]; stmt.LeftError == "" || stmt.RightError == "" {
+		t.Errorf("Expected errors on both sides, got %+v", stmt)
+	}
+	if report.Statements[1].Differences() != 0 {
+		t.Errorf("Expected identical results, got %+v", report.Statements[1])
+	}
+
+	set := CompareSets(
+		database.ResultSet{Columns: []string{"id", "name"}},
+		database.ResultSet{Columns: []string{"ID", "email"}},
+		nil)
+	if set.Differences() != 1 || set.LeftColumns == nil {
+		t.Errorf("Expected a column difference, got %+v", set)
+	}
+}
+
+func TestCompareSetsKeysDoNotCollide(t *testing.T) {
+	columns := []string{"a", "b", "v"}
+	left := database.ResultSet{Columns: columns, Rows: [][]interface{}{
+		{nil, "x", int64(1)},
+		{"a, b", "", int64(2)},
+	}}
+	right := database.ResultSet{Columns: columns, Rows: [][]interface{}{
+		{"NULL", "x", int64(1)},
+		{"a", "b", int64(2)},
+	}}
+
+	set := CompareSets(left, right, []string{"a", "b"})
+	if set.Error != "" {
+		t.Fatalf("Expected no error, got %s", set.Error)
+	}
+	if len(set.Removed) != 2 || len(set.Added) != 2 || len(set.Changed) != 0 {
+		t.Errorf("Expected distinct keys to stay unmatched, got %+v", set)
+	}
+}
+
+func TestEqualValues(t *testing.T) {
+	tests := []struct {
+		left, right interface{}
+		expected    bool
+	}{
+		{int64(1), float64(1), true},
+		{database.Decimal("2.50"), 2.5, true},
+		{"a", "a", true},
+		{nil, nil, true},
+		{nil, "NULL", false},
+		{int64(1), int64(2), false},
+	}
+	for _, tt := range tests {
+		if got := equalValues(tt.left, tt.right); got != tt.expected {
+			t.Errorf("equalValues(%v, %v) = %v, expected %v", tt.left, tt.right, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	differ := newTestDiffer(t, []string{"id"},
+		"(1, 'ann', 1.5), (2, 'bob', 2)",
+		"(1, 'ann', 1.5), (2, 'robert', 2)")
+	report := diffScript(t, differ, "SELECT * FROM users")
+
+	var buf bytes.Buffer
+	if err := report.WriteUnified(&buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, line := range []string{"--- left", "+++ right", " id|name|score", "-2|bob|2", "+2|robert|2"} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected unified output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var document struct {
+		Differences int `json:"differences"`
+		Statements  []struct {
+			ResultSets []struct {
+				Changed []struct {
+					Right   map[string]interface{} `json:"right"`
+					Columns []string               `json:"changed_columns"`
+				} `json:"changed"`
+			} `json:"result_sets"`
+		} `json:"statements"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, buf.String())
+	}
+	if document.Differences != 1 || document.Statements[0].ResultSets[0].Changed[0].Right["name"] != "robert" {
+		t.Errorf("Unexpected JSON report:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.WriteTable(output.NewFormatter("table", &buf)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "1 changed") || !strings.Contains(buf.String(), "robert") {
+		t.Errorf("Unexpected table report:\n%s", buf.String())
+	}
+}
+
+func TestWriteUnifiedEscapes(t *testing.T) {
+	differ := newTestDiffer(t, []string{"id"},
+		"(1, 'a|b', NULL)",
+		"(1, 'NULL', 1)")
+	report := diffScript(t, differ, "SELECT * FROM users")
+
+	var buf bytes.Buffer
+	if err := report.WriteUnified(&buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Separators inside values are escaped and NULL differs from 'NULL'
+	for _, line := range []string{`-1|a\|b|\N`, "+1|NULL|1"} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected unified output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
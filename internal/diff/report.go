@@ -0,0 +1,257 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gosqlpp/internal/output"
+)
+
+// Markers of differing rows in table and unified output
+const (
+	markRemoved     = "-"
+	markAdded       = "+"
+	markChangedFrom = "<"
+	markChangedTo   = ">"
+)
+
+// Styles a report can be written in
+const (
+	StyleTable   = "table"
+	StyleJSON    = "json"
+	StyleUnified = "unified"
+)
+
+// Styles lists the styles a report can be written in
+var Styles = []string{StyleTable, StyleJSON, StyleUnified}
+
+// Summary returns a one-line description of the statement's differences
+func (s StatementDiff) Summary() string {
+	location := fmt.Sprintf("%s:%d", s.FileName, s.LineNumber)
+	var parts []string
+	if s.LeftError != "" {
+		parts = append(parts, "error on the left: "+s.LeftError)
+	}
+	if s.RightError != "" {
+		parts = append(parts, "error on the right: "+s.RightError)
+	}
+	for i, set := range s.ResultSets {
+		summary := set.Summary()
+		if len(s.ResultSets) > 1 {
+			summary = fmt.Sprintf("result set %d: %s", i+1, summary)
+		}
+		parts = append(parts, summary)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no rows to compare")
+	}
+	return location + ": " + strings.Join(parts, "; ")
+}
+
+// Summary returns a one-line description of the result set's differences
+func (s SetDiff) Summary() string {
+	var summary string
+	switch {
+	case s.Error != "":
+		summary = s.Error
+	case s.LeftColumns != nil || s.RightColumns != nil:
+		summary = fmt.Sprintf("columns differ: (%s) on the left, (%s) on the right",
+			strings.Join(s.LeftColumns, ", "), strings.Join(s.RightColumns, ", "))
+	case s.Differences() == 0:
+		summary = fmt.Sprintf("identical (%d rows)", s.LeftRows)
+	default:
+		summary = fmt.Sprintf("%d removed, %d added, %d changed", len(s.Removed), len(s.Added), len(s.Changed))
+	}
+	if s.Truncated {
+		summary += fmt.Sprintf(" (truncated: compared %d and %d rows)", s.LeftRows, s.RightRows)
+	}
+	return summary
+}
+
+// Summary returns the closing line of a report
+func (r *Report) Summary() string {
+	differing := 0
+	for _, stmt := range r.Statements {
+		if stmt.Differences() > 0 {
+			differing++
+		}
+	}
+	return fmt.Sprintf("%d of %d statement(s) differ between %s and %s (%d difference(s))",
+		differing, len(r.Statements), r.Left, r.Right, r.Differences())
+}
+
+// WriteTable writes a summary line for each statement, followed by a table
+// of its differing rows, through formatter. The first column marks removed
+// rows with -, added rows with + and changed rows with < for the left values
+// and > for the right ones.
+func (r *Report) WriteTable(formatter *output.Formatter) error {
+	for _, stmt := range r.Statements {
+		if err := formatter.WriteMessage(stmt.Summary()); err != nil {
+			return err
+		}
+		for _, set := range stmt.ResultSets {
+			records := set.records()
+			if len(records) == 0 {
+				continue
+			}
+			if err := formatter.FormatData(records); err != nil {
+				return err
+			}
+			if err := formatter.WriteMessage(""); err != nil {
+				return err
+			}
+		}
+	}
+	return formatter.WriteMessage(r.Summary())
+}
+
+// records lists the differing rows of a result set, marked as in WriteTable
+func (s SetDiff) records() []output.Record {
+	var records []output.Record
+	add := func(mark string, row []interface{}) {
+		record := output.Record{{Key: "", Value: mark}}
+		for i, col := range s.Columns {
+			record = append(record, output.Field{Key: col, Value: rowValue(row, i)})
+		}
+		records = append(records, record)
+	}
+	for _, row := range s.Removed {
+		add(markRemoved, row)
+	}
+	for _, row := range s.Added {
+		add(markAdded, row)
+	}
+	for _, change := range s.Changed {
+		add(markChangedFrom, change.Left)
+		add(markChangedTo, change.Right)
+	}
+	return records
+}
+
+// WriteJSON writes the report as an indented JSON document
+func (r *Report) WriteJSON(w io.Writer) error {
+	document := struct {
+		*Report
+		Differences int `json:"differences"`
+	}{r, r.Differences()}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// MarshalJSON encodes the result set with its differing rows as objects
+// keyed by column name
+func (s SetDiff) MarshalJSON() ([]byte, error) {
+	type changeJSON struct {
+		Left    output.Record `json:"left"`
+		Right   output.Record `json:"right"`
+		Columns []string      `json:"changed_columns"`
+	}
+	type setJSON SetDiff
+	document := struct {
+		setJSON
+		Removed []output.Record `json:"removed"`
+		Added   []output.Record `json:"added"`
+		Changed []changeJSON    `json:"changed"`
+	}{setJSON: setJSON(s), Removed: []output.Record{}, Added: []output.Record{}, Changed: []changeJSON{}}
+
+	for _, row := range s.Removed {
+		document.Removed = append(document.Removed, s.record(row))
+	}
+	for _, row := range s.Added {
+		document.Added = append(document.Added, s.record(row))
+	}
+	for _, change := range s.Changed {
+		document.Changed = append(document.Changed, changeJSON{
+			Left:    s.record(change.Left),
+			Right:   s.record(change.Right),
+			Columns: change.Columns,
+		})
+	}
+	return json.Marshal(document)
+}
+
+// record converts a row to a record keyed by the result set's columns
+func (s SetDiff) record(row []interface{}) output.Record {
+	record := make(output.Record, len(s.Columns))
+	for i, col := range s.Columns {
+		record[i] = output.Field{Key: col, Value: rowValue(row, i)}
+	}
+	return record
+}
+
+// WriteUnified writes the report in the style of a unified diff: a hunk
+// per statement, starting with the column names, with removed rows and the
+// left side of changed rows prefixed by - and added rows and the right side
+// of changed rows by +. Values are separated by |, with backslash escapes
+// for |, backslashes and line breaks inside values, and NULL written as \N
+// so that it differs from the text 'NULL'.
+func (r *Report) WriteUnified(w io.Writer) error {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", r.Left, r.Right)
+	for _, stmt := range r.Statements {
+		if stmt.Differences() == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "@@ %s:%d @@\n", stmt.FileName, stmt.LineNumber)
+		if stmt.LeftError != "" {
+			fmt.Fprintf(w, "-error: %s\n", stmt.LeftError)
+		}
+		if stmt.RightError != "" {
+			fmt.Fprintf(w, "+error: %s\n", stmt.RightError)
+		}
+		for _, set := range stmt.ResultSets {
+			if set.Differences() == 0 {
+				continue
+			}
+			if set.Error != "" {
+				fmt.Fprintf(w, "!%s\n", set.Error)
+				continue
+			}
+			if set.LeftColumns != nil || set.RightColumns != nil {
+				fmt.Fprintf(w, "-%s\n+%s\n", columnsText(set.LeftColumns), columnsText(set.RightColumns))
+				continue
+			}
+			fmt.Fprintf(w, " %s\n", columnsText(set.Columns))
+			for _, row := range set.Removed {
+				fmt.Fprintf(w, "-%s\n", rowText(row, len(set.Columns)))
+			}
+			for _, row := range set.Added {
+				fmt.Fprintf(w, "+%s\n", rowText(row, len(set.Columns)))
+			}
+			for _, change := range set.Changed {
+				fmt.Fprintf(w, "-%s\n", rowText(change.Left, len(set.Columns)))
+				fmt.Fprintf(w, "+%s\n", rowText(change.Right, len(set.Columns)))
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "# %s\n", r.Summary())
+	return err
+}
+
+// unifiedEscaper escapes the separator, backslashes and line breaks in the
+// values of unified output
+var unifiedEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", `\n`, "\r", `\r`)
+
+// columnsText joins escaped column names with |
+func columnsText(columns []string) string {
+	names := make([]string, len(columns))
+	for i, name := range columns {
+		names[i] = unifiedEscaper.Replace(name)
+	}
+	return strings.Join(names, "|")
+}
+
+// rowText joins the escaped text of a row's values with |, writing NULL as \N
+func rowText(row []interface{}, columns int) string {
+	values := make([]string, columns)
+	for i := range values {
+		if val := rowValue(row, i); val != nil {
+			values[i] = unifiedEscaper.Replace(ValueText(val))
+		} else {
+			values[i] = `\N`
+		}
+	}
+	return strings.Join(values, "|")
+}
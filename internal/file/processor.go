@@ -22,12 +22,59 @@ type Statement struct {
 	Location  preprocessor.SourceLocation
 }
 
+// IsCommand reports whether the statement is a schema or processor command
+// rather than SQL
+func (s Statement) IsCommand() bool {
+	return schema.IsSchemaCommand(s.SQL) || isProcessorCommand(s.SQL)
+}
+
+// Summary output modes
+const (
+	SummaryNone = ""
+	SummaryText = "text"
+	SummaryJSON = "json"
+)
+
 // Processor handles file processing and SQL execution
 type Processor struct {
 	executor     *database.Executor
 	formatter    *output.Formatter
 	introspector *schema.Introspector
 	endOnError   bool
+
+	// timing prints the elapsed time after each statement
+	timing bool
+	// summaryMode selects how the end-of-run summary is printed
+	summaryMode string
+	// slowest is the number of slowest statements listed in the summary
+	slowest int
+	// summary collects statistics for the current run; runDepth tracks
+	// nested Process* calls so the summary is printed once per run
+	summary  *Summary
+	runDepth int
+
+	// explain shows query plans instead of executing SELECT/DML statements;
+	// explainNext does so for the next statement only (@explain). Statements
+	// without a plan, such as DDL, are skipped unless explainRunOthers is set.
+	explain          bool
+	explainNext      bool
+	explainRunOthers bool
+
+	// confirm asks the user whether to run destructive statements on a
+	// protected connection; nil means they are refused without asking
+	confirm func(prompt string) bool
+}
+
+// processorCommands are script commands handled by the processor itself
+var processorCommands = map[string]bool{
+	"@explain": true,
+	"@spool":   true,
+}
+
+// isProcessorCommand checks if a line contains a processor command
+func isProcessorCommand(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) > 0 && processorCommands[fields[0]]
 }
 
 // NewProcessor creates a new file processor
@@ -40,17 +87,77 @@ func NewProcessor(executor *database.Executor, formatter *output.Formatter, intr
 	}
 }
 
-// ProcessFile processes a single SQL file
-func (p *Processor) ProcessFile(filename string) error {
-	// Create preprocessor and process file
-	prep := preprocessor.NewPreprocessor()
-	lines, locations, err := prep.ProcessFile(filename)
+// SetTiming enables printing the elapsed time after each statement
+func (p *Processor) SetTiming(enabled bool) {
+	p.timing = enabled
+}
+
+// SetSummary selects the end-of-run summary mode (SummaryNone, SummaryText
+// or SummaryJSON) and how many of the slowest statements it lists
+func (p *Processor) SetSummary(mode string, slowest int) {
+	p.summaryMode = mode
+	p.slowest = slowest
+}
+
+// SetExplain enables showing query plans instead of executing SELECT and DML
+// statements. Other statements, such as DDL, are skipped unless
+// SetExplainRunOthers is enabled.
+func (p *Processor) SetExplain(enabled bool) {
+	p.explain = enabled
+}
+
+// SetExplainRunOthers makes plan mode execute statements that have no query
+// plan, such as DDL, so that later plans can refer to the objects they create
+func (p *Processor) SetExplainRunOthers(enabled bool) {
+	p.explainRunOthers = enabled
+}
+
+// SetConfirm sets the function that asks the user whether to run the
+// destructive statements found on a protected connection
+func (p *Processor) SetConfirm(confirm func(prompt string) bool) {
+	p.confirm = confirm
+}
+
+// beginRun starts collecting statistics when the outermost run begins
+func (p *Processor) beginRun() {
+	if p.runDepth == 0 {
+		p.summary = NewSummary()
+	}
+	p.runDepth++
+}
+
+// endRun prints the summary when the outermost run ends. Like the timing of
+// each statement, it goes to standard error so that it never mixes with the
+// results on standard output, in an output file or in a spool file.
+func (p *Processor) endRun() {
+	p.runDepth--
+	if p.runDepth > 0 || p.summaryMode == SummaryNone {
+		return
+	}
+
+	p.summary.Finish(p.slowest)
+	var err error
+	if p.summaryMode == SummaryJSON {
+		err = p.summary.WriteJSON(os.Stderr)
+	} else {
+		err = p.summary.WriteText(os.Stderr)
+	}
 	if err != nil {
-		return fmt.Errorf("preprocessing failed for %s: %w", filename, err)
+		fmt.Fprintf(os.Stderr, "Error writing summary: %v\n", err)
 	}
+}
 
-	// Parse statements from preprocessed lines
-	statements, err := p.parseStatementsFromLines(lines, locations)
+// Summary returns the statistics of the current or most recent run
+func (p *Processor) Summary() *Summary {
+	return p.summary
+}
+
+// ProcessFile processes a single SQL file
+func (p *Processor) ProcessFile(filename string) error {
+	p.beginRun()
+	defer p.endRun()
+
+	statements, err := LoadFile(filename)
 	if err != nil {
 		return err
 	}
@@ -59,8 +166,24 @@ func (p *Processor) ProcessFile(filename string) error {
 	return p.executeStatements(statements)
 }
 
+// LoadFile preprocesses a file and parses its statements
+func LoadFile(filename string) ([]Statement, error) {
+	// Create preprocessor and process file
+	prep := preprocessor.NewPreprocessor()
+	lines, locations, err := prep.ProcessFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("preprocessing failed for %s: %w", filename, err)
+	}
+
+	// Parse statements from preprocessed lines
+	return parseStatementsFromLines(lines, locations)
+}
+
 // ProcessStdin processes SQL commands from standard input
 func (p *Processor) ProcessStdin() error {
+	p.beginRun()
+	defer p.endRun()
+
 	// Create preprocessor and process stdin
 	prep := preprocessor.NewPreprocessor()
 	lines, locations, err := prep.ProcessReader(os.Stdin, "<stdin>")
@@ -69,7 +192,7 @@ func (p *Processor) ProcessStdin() error {
 	}
 
 	// Parse statements from preprocessed lines
-	statements, err := p.parseStatementsFromLines(lines, locations)
+	statements, err := parseStatementsFromLines(lines, locations)
 	if err != nil {
 		return err
 	}
@@ -80,6 +203,9 @@ func (p *Processor) ProcessStdin() error {
 
 // ProcessStdinText processes SQL commands from provided text (instead of reading from stdin)
 func (p *Processor) ProcessStdinText(inputText string) error {
+	p.beginRun()
+	defer p.endRun()
+
 	// Create preprocessor and process the input text
 	prep := preprocessor.NewPreprocessor()
 	reader := strings.NewReader(inputText)
@@ -89,7 +215,7 @@ func (p *Processor) ProcessStdinText(inputText string) error {
 	}
 
 	// Parse statements from preprocessed lines
-	statements, err := p.parseStatementsFromLines(lines, locations)
+	statements, err := parseStatementsFromLines(lines, locations)
 	if err != nil {
 		return err
 	}
@@ -100,6 +226,9 @@ func (p *Processor) ProcessStdinText(inputText string) error {
 
 // ProcessDirectory processes all .sql files in a directory
 func (p *Processor) ProcessDirectory(dirPath string, newerThan time.Time) error {
+	p.beginRun()
+	defer p.endRun()
+
 	// Find all .sql files
 	files, err := findSQLFiles(dirPath, newerThan)
 	if err != nil {
@@ -107,21 +236,37 @@ func (p *Processor) ProcessDirectory(dirPath string, newerThan time.Time) error
 	}
 
 	if len(files) == 0 {
-		fmt.Printf("No SQL files found in directory: %s\n", dirPath)
+		fmt.Fprintf(os.Stderr, "No SQL files found in directory: %s\n", dirPath)
 		return nil
 	}
 
-	fmt.Printf("Found %d SQL files to process\n", len(files))
+	fmt.Fprintf(os.Stderr, "Found %d SQL files to process\n", len(files))
+
+	// On protected connections, check every file before anything runs
+	if p.executor.GuardsDestructive() {
+		var statements []Statement
+		for _, file := range files {
+			// Files that fail to load are reported when they are processed
+			fileStatements, err := LoadFile(file)
+			if err != nil {
+				continue
+			}
+			statements = append(statements, fileStatements...)
+		}
+		if err := p.guardDestructive(statements); err != nil {
+			return err
+		}
+	}
 
 	// Process each file
 	for i, file := range files {
-		fmt.Printf("\n[%d/%d] Processing: %s\n", i+1, len(files), file)
+		fmt.Fprintf(os.Stderr, "\n[%d/%d] Processing: %s\n", i+1, len(files), file)
 
 		if err := p.ProcessFile(file); err != nil {
 			if p.endOnError {
 				return fmt.Errorf("error processing %s: %w", file, err)
 			}
-			fmt.Printf("Error processing %s: %v\n", file, err)
+			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", file, err)
 		}
 	}
 
@@ -129,15 +274,15 @@ func (p *Processor) ProcessDirectory(dirPath string, newerThan time.Time) error
 }
 
 // parseStatementsFromLines parses SQL statements from preprocessed lines
-func (p *Processor) parseStatementsFromLines(lines []string, locations []preprocessor.SourceLocation) ([]Statement, error) {
+func parseStatementsFromLines(lines []string, locations []preprocessor.SourceLocation) ([]Statement, error) {
 	var statements []Statement
 	var currentStatement strings.Builder
 	var startLine int
 	var startLocation preprocessor.SourceLocation
 
 	for i, line := range lines {
-		// Check if this line is a schema command
-		if schema.IsSchemaCommand(line) {
+		// Check if this line is a schema or processor command
+		if schema.IsSchemaCommand(line) || isProcessorCommand(line) {
 			// End current statement if exists
 			if currentStatement.Len() > 0 {
 				statements = append(statements, Statement{
@@ -207,8 +352,61 @@ func (p *Processor) parseStatementsFromLines(lines []string, locations []preproc
 	return statements, nil
 }
 
+// DestructiveStatement is a statement flagged by the destructive-statement guard
+type DestructiveStatement struct {
+	Statement Statement
+	Reason    string
+}
+
+// FindDestructive lists the destructive statements that are not marked with
+// an @allow-destructive hint
+func FindDestructive(statements []Statement) []DestructiveStatement {
+	var flagged []DestructiveStatement
+	for _, stmt := range statements {
+		if stmt.IsCommand() {
+			continue
+		}
+		if database.ParseHints(stmt.SQL).Has(database.AllowDestructiveHint) {
+			continue
+		}
+		if reason := database.DestructiveReason(stmt.SQL); reason != "" {
+			flagged = append(flagged, DestructiveStatement{Statement: stmt, Reason: reason})
+		}
+	}
+	return flagged
+}
+
+// guardDestructive lists the destructive statements about to run on a
+// protected connection and asks for confirmation. Without confirmation the
+// run is refused before any statement executes.
+func (p *Processor) guardDestructive(statements []Statement) error {
+	if !p.executor.GuardsDestructive() {
+		return nil
+	}
+	flagged := FindDestructive(statements)
+	if len(flagged) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Destructive statements on a protected connection:\n")
+	for _, f := range flagged {
+		fmt.Fprintf(os.Stderr, "  %s:%d: %s\n", f.Statement.Location.OriginalFile, f.Statement.Location.OriginalLine, f.Reason)
+	}
+
+	if p.confirm != nil && p.confirm(fmt.Sprintf("Run %d destructive statement(s)? [y/N] ", len(flagged))) {
+		p.executor.AllowDestructive(true)
+		return nil
+	}
+	return fmt.Errorf("refusing to run %d destructive statement(s) on a protected connection; "+
+		"use --yes-i-mean-it or add a -- @%s hint to reviewed statements", len(flagged), database.AllowDestructiveHint)
+}
+
 // executeStatements executes a list of SQL statements
 func (p *Processor) executeStatements(statements []Statement) error {
+	if err := p.guardDestructive(statements); err != nil {
+		return err
+	}
+
 	for _, stmt := range statements {
 		// Skip empty statements
 		if strings.TrimSpace(stmt.SQL) == "" {
@@ -218,23 +416,59 @@ func (p *Processor) executeStatements(statements []Statement) error {
 		// Check if this is a schema command
 		if schema.IsSchemaCommand(stmt.SQL) {
 			command, filter := schema.ParseSchemaCommand(stmt.SQL)
-			if err := p.introspector.ProcessSchemaCommand(command, filter); err != nil {
+			p.formatter.SetCommandLocation(output.CommandLocation{
+				File: stmt.Location.OriginalFile,
+				Line: stmt.Location.OriginalLine,
+				SQL:  strings.TrimSpace(stmt.SQL),
+			})
+			err := p.introspector.ProcessSchemaCommand(command, filter)
+			p.formatter.SetCommandLocation(output.CommandLocation{})
+			if err != nil {
 				if p.endOnError {
 					return fmt.Errorf("schema command error: %w", err)
 				}
-				fmt.Printf("Schema command error: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Schema command error: %v\n", err)
 			}
 			continue
 		}
 
-		// Execute regular SQL statement using original file location for error reporting
-		result := p.executor.Execute(stmt.SQL, stmt.Location.OriginalLine, stmt.Location.OriginalFile)
+		// Check if this is a processor command
+		if isProcessorCommand(stmt.SQL) {
+			if err := p.processCommand(stmt.SQL); err != nil {
+				if p.endOnError {
+					return fmt.Errorf("%s:%d: %w", stmt.Location.OriginalFile, stmt.Location.OriginalLine, err)
+				}
+				fmt.Fprintf(os.Stderr, "%s:%d: error: %v\n", stmt.Location.OriginalFile, stmt.Location.OriginalLine, err)
+			}
+			continue
+		}
+
+		// Execute regular SQL statement using original file location for error
+		// reporting, or show its plan when explaining
+		explaining := p.explain || p.explainNext
+		p.explainNext = false
+		var result *database.ExecutionResult
+		switch {
+		case explaining && database.IsExplainable(stmt.SQL):
+			result = p.executor.Explain(stmt.SQL, stmt.Location.OriginalLine, stmt.Location.OriginalFile)
+		case explaining && !p.explainRunOthers:
+			fmt.Fprintf(os.Stderr, "%s:%d: skipped: statement has no query plan (use --explain-run-ddl to run it)\n",
+				stmt.Location.OriginalFile, stmt.Location.OriginalLine)
+			continue
+		default:
+			result = p.executor.Execute(stmt.SQL, stmt.Location.OriginalLine, stmt.Location.OriginalFile)
+		}
+		p.summary.Record(result)
 
 		// Format and output result
 		if err := p.formatter.FormatResult(result); err != nil {
 			return fmt.Errorf("error formatting result: %w", err)
 		}
 
+		if p.timing {
+			fmt.Fprintf(os.Stderr, "%s\n", database.FormatTiming(result))
+		}
+
 		// Check for errors
 		if result.Error != nil && p.endOnError {
 			return fmt.Errorf("execution stopped due to error")
@@ -244,6 +478,45 @@ func (p *Processor) executeStatements(statements []Statement) error {
 	return nil
 }
 
+// processCommand runs a processor command such as @explain or @spool
+func (p *Processor) processCommand(line string) error {
+	fields := strings.Fields(line)
+	command := fields[0]
+	argument := ""
+	if len(fields) > 1 {
+		argument = strings.ToLower(strings.Trim(fields[1], `"'`))
+	}
+
+	switch command {
+	case "@spool":
+		// Spool paths keep their case; only "off" is a keyword
+		switch argument {
+		case "":
+			return fmt.Errorf("@spool needs a file name or off")
+		case "off":
+			return p.formatter.Unspool()
+		default:
+			return p.formatter.Spool(strings.Trim(fields[1], `"'`))
+		}
+	case "@explain":
+		switch argument {
+		case "":
+			p.explainNext = true
+		case "on":
+			p.explain = true
+		case "off":
+			p.explain = false
+			p.explainNext = false
+		default:
+			return fmt.Errorf("invalid @explain argument %q, expected on or off", argument)
+		}
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+
+	return nil
+}
+
 // findSQLFiles finds all .sql files in a directory, optionally filtering by modification time
 func findSQLFiles(dirPath string, newerThan time.Time) ([]string, error) {
 	var files []string
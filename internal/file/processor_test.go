@@ -2,6 +2,9 @@ package file
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -88,3 +91,194 @@ func TestStdinInputValidation(t *testing.T) {
 		})
 	}
 }
+
+// newTestProcessor creates a processor backed by an in-memory SQLite database
+// that writes its output to buf
+func newTestProcessor(t *testing.T, format string, buf *bytes.Buffer) *Processor {
+	t.Helper()
+
+	manager := database.NewManager()
+	t.Cleanup(func() { manager.CloseAll() })
+
+	connConfig := config.Connection{
+		Driver:           "sqlite3",
+		ConnectionString: ":memory:",
+	}
+	if err := manager.Connect("test", connConfig); err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	conn, err := manager.GetConnection("test")
+	if err != nil {
+		t.Fatalf("Failed to get test connection: %v", err)
+	}
+	// Keep a single connection so the in-memory database survives between statements
+	conn.DB.SetMaxOpenConns(1)
+
+	formatter := output.NewFormatter(format, buf)
+	return NewProcessor(database.NewExecutor(conn), formatter, schema.NewIntrospector(conn, formatter), false)
+}
+
+func TestProcessExplainCommand(t *testing.T) {
+	var buf bytes.Buffer
+	processor := newTestProcessor(t, "table", &buf)
+
+	input := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+go
+@explain
+SELECT * FROM users WHERE id = 1;
+go
+SELECT count(*) AS n FROM users;
+go
+`
+	if err := processor.ProcessStdinText(input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "Query plan for <stdin>:4 (sqlite3)") {
+		t.Errorf("Expected a query plan for the statement after @explain, got: %s", output)
+	}
+	if strings.Count(output, "Query plan") != 1 {
+		t.Errorf("Expected @explain to apply to the next statement only, got: %s", output)
+	}
+	if !strings.Contains(output, "(1 rows)") {
+		t.Errorf("Expected the last statement to be executed, got: %s", output)
+	}
+}
+
+func TestProcessExplainMode(t *testing.T) {
+	var buf bytes.Buffer
+	processor := newTestProcessor(t, "json", &buf)
+	processor.SetExplain(true)
+	processor.SetExplainRunOthers(true)
+
+	input := "CREATE TABLE users (id INTEGER);\ngo\nINSERT INTO users VALUES (1);\ngo\n@explain off\nSELECT count(*) AS n FROM users;\ngo\n"
+	if err := processor.ProcessStdinText(input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, `"line": 3`) {
+		t.Errorf("Expected INSERT to be explained, got: %s", output)
+	}
+	// The INSERT was only explained, so the count is zero
+	if !strings.Contains(output, `"n": 0`) {
+		t.Errorf("Expected statements after @explain off to run, got: %s", output)
+	}
+}
+
+func TestProcessExplainModeSkipsDDL(t *testing.T) {
+	var buf bytes.Buffer
+	processor := newTestProcessor(t, "table", &buf)
+	processor.SetExplain(true)
+
+	input := "CREATE TABLE users (id INTEGER);\ngo\n"
+	if err := processor.ProcessStdinText(input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The CREATE TABLE has no query plan, so it is skipped
+	result := processor.executor.Execute("SELECT * FROM users", 1, "test.sql")
+	if result.Error == nil {
+		t.Error("Expected CREATE TABLE not to have run")
+	}
+}
+
+func TestProcessRefusesDestructiveStatements(t *testing.T) {
+	var buf bytes.Buffer
+	processor := newTestProcessor(t, "table", &buf)
+	processor.executor.SetProtected(true)
+
+	input := "CREATE TABLE users (id INTEGER);\ngo\nDELETE FROM users;\ngo\n"
+	err := processor.ProcessStdinText(input)
+	if err == nil || !strings.Contains(err.Error(), "1 destructive statement") {
+		t.Fatalf("Expected the run to be refused, got %v", err)
+	}
+
+	// Nothing runs when the run is refused
+	result := processor.executor.Execute("SELECT * FROM users", 1, "test.sql")
+	if result.Error == nil {
+		t.Error("Expected CREATE TABLE not to have run")
+	}
+}
+
+func TestProcessConfirmsDestructiveStatements(t *testing.T) {
+	var buf bytes.Buffer
+	processor := newTestProcessor(t, "table", &buf)
+	processor.executor.SetProtected(true)
+
+	var prompts []string
+	processor.SetConfirm(func(prompt string) bool {
+		prompts = append(prompts, prompt)
+		return true
+	})
+
+	input := "CREATE TABLE users (id INTEGER);\ngo\nDELETE FROM users;\ngo\n-- @allow-destructive\nDROP TABLE users;\ngo\n"
+	if err := processor.ProcessStdinText(input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "Run 1 destructive statement") {
+		t.Errorf("Expected a single confirmation for the unhinted statement, got %v", prompts)
+	}
+	if strings.Contains(buf.String(), "Error") {
+		t.Errorf("Expected confirmed statements to run, got: %s", buf.String())
+	}
+}
+
+func TestProcessSpoolCommand(t *testing.T) {
+	var buf bytes.Buffer
+	processor := newTestProcessor(t, "table", &buf)
+	spoolPath := filepath.Join(t.TempDir(), "Users.csv")
+
+	input := "CREATE TABLE users (id INTEGER, name TEXT);\ngo\nINSERT INTO users VALUES (1, 'john');\ngo\n" +
+		"@spool " + spoolPath + "\nSELECT id, name FROM users;\ngo\n@spool off\nSELECT count(*) AS n FROM users;\ngo\n"
+	if err := processor.ProcessStdinText(input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spooled, err := os.ReadFile(spoolPath)
+	if err != nil {
+		t.Fatalf("Expected the spool file to be written: %v", err)
+	}
+	if string(spooled) != "id,name\n1,john\n" {
+		t.Errorf("Expected the spooled result as CSV, got: %q", spooled)
+	}
+	if strings.Contains(buf.String(), "john") || !strings.Contains(buf.String(), "(1 rows)") {
+		t.Errorf("Expected only results after @spool off on the regular output, got: %s", buf.String())
+	}
+}
+
+func TestProcessSchemaCommandEnvelopeLocation(t *testing.T) {
+	var buf bytes.Buffer
+	processor := newTestProcessor(t, "json-envelope", &buf)
+
+	input := `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+go
+@schema-tables users
+go
+`
+	if err := processor.ProcessStdinText(input); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := processor.formatter.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var document struct {
+		Statements []struct {
+			File string `json:"file"`
+			Line int    `json:"line"`
+			SQL  string `json:"sql"`
+		} `json:"statements"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("Expected a JSON document, got %v: %s", err, buf.String())
+	}
+	if len(document.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d: %s", len(document.Statements), buf.String())
+	}
+	command := document.Statements[1]
+	if command.File != "<stdin>" || command.Line != 3 || command.SQL != "@schema-tables users" {
+		t.Errorf("Expected the schema command's location, got %+v", command)
+	}
+}
@@ -0,0 +1,132 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gosqlpp/internal/database"
+)
+
+// StatementTiming records how long a single statement took
+type StatementTiming struct {
+	FileName   string        `json:"file"`
+	LineNumber int           `json:"line"`
+	Statement  string        `json:"statement"`
+	Duration   time.Duration `json:"-"`
+	DurationMs float64       `json:"duration_ms"`
+	Failed     bool          `json:"failed"`
+}
+
+// Summary collects execution statistics over a run of one or more files
+type Summary struct {
+	StatementsExecuted int               `json:"statements_executed"`
+	Succeeded          int               `json:"succeeded"`
+	Failed             int               `json:"failed"`
+	RowsReturned       int64             `json:"rows_returned"`
+	RowsAffected       int64             `json:"rows_affected"`
+	TotalTime          time.Duration     `json:"-"`
+	TotalTimeMs        float64           `json:"total_time_ms"`
+	RowsPerSecond      float64           `json:"rows_per_second"`
+	Slowest            []StatementTiming `json:"slowest"`
+
+	startTime time.Time
+	timings   []StatementTiming
+}
+
+// NewSummary creates an empty summary whose total time starts now
+func NewSummary() *Summary {
+	return &Summary{startTime: time.Now()}
+}
+
+// Record adds the outcome of an executed statement to the summary
+func (s *Summary) Record(result *database.ExecutionResult) {
+	s.StatementsExecuted++
+	if result.Error != nil {
+		s.Failed++
+	} else {
+		s.Succeeded++
+		s.RowsReturned += result.TotalRows()
+		s.RowsAffected += result.RowsAffected
+	}
+
+	s.timings = append(s.timings, StatementTiming{
+		FileName:   result.FileName,
+		LineNumber: result.LineNumber,
+		Statement:  result.Statement,
+		Duration:   result.Duration,
+		DurationMs: durationMs(result.Duration),
+		Failed:     result.Error != nil,
+	})
+}
+
+// Finish stops the run clock, works out the throughput in rows returned
+// and affected per second, and keeps the slowest n statements
+func (s *Summary) Finish(slowest int) {
+	s.TotalTime = time.Since(s.startTime)
+	s.TotalTimeMs = durationMs(s.TotalTime)
+	if seconds := s.TotalTime.Seconds(); seconds > 0 {
+		s.RowsPerSecond = float64(s.RowsReturned+s.RowsAffected) / seconds
+	}
+
+	sorted := make([]StatementTiming, len(s.timings))
+	copy(sorted, s.timings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Duration > sorted[j].Duration
+	})
+	if slowest < len(sorted) {
+		sorted = sorted[:slowest]
+	}
+	s.Slowest = sorted
+}
+
+// WriteText writes the summary in a human readable form
+func (s *Summary) WriteText(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("\n=== Execution Summary ===\n")
+	fmt.Fprintf(&b, "Statements executed: %d (%d succeeded, %d failed)\n", s.StatementsExecuted, s.Succeeded, s.Failed)
+	fmt.Fprintf(&b, "Rows returned:       %d\n", s.RowsReturned)
+	fmt.Fprintf(&b, "Rows affected:       %d\n", s.RowsAffected)
+	fmt.Fprintf(&b, "Total time:          %.3f ms\n", s.TotalTimeMs)
+	fmt.Fprintf(&b, "Throughput:          %.1f rows/s\n", s.RowsPerSecond)
+
+	if len(s.Slowest) > 0 {
+		b.WriteString("Slowest statements:\n")
+		for i, timing := range s.Slowest {
+			fmt.Fprintf(&b, "  %d. %10.3f ms  %s:%d  %s\n", i+1, timing.DurationMs,
+				timing.FileName, timing.LineNumber, statementPreview(timing.Statement))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the summary as a JSON document
+func (s *Summary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// statementPreview returns the first line of a statement, shortened for display
+func statementPreview(statement string) string {
+	const maxLength = 60
+
+	preview := strings.TrimSpace(statement)
+	if i := strings.IndexByte(preview, '\n'); i >= 0 {
+		preview = preview[:i] + " ..."
+	}
+	if runes := []rune(preview); len(runes) > maxLength {
+		preview = string(runes[:maxLength-3]) + "..."
+	}
+	return preview
+}
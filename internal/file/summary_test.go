@@ -0,0 +1,123 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"gosqlpp/internal/database"
+)
+
+func TestSummaryRecord(t *testing.T) {
+	summary := NewSummary()
+
+	summary.Record(&database.ExecutionResult{
+		Statement:  "SELECT * FROM users",
+		FileName:   "a.sql",
+		LineNumber: 1,
+		Columns:    []string{"id"},
+		Rows:       [][]interface{}{{1}, {2}},
+		Duration:   10 * time.Millisecond,
+	})
+	summary.Record(&database.ExecutionResult{
+		Statement:    "UPDATE users SET name = 'x'",
+		FileName:     "a.sql",
+		LineNumber:   5,
+		RowsAffected: 3,
+		Duration:     30 * time.Millisecond,
+	})
+	summary.Record(&database.ExecutionResult{
+		Statement:  "SELECT * FROM missing",
+		FileName:   "b.sql",
+		LineNumber: 2,
+		Error:      fmt.Errorf("no such table"),
+		Duration:   time.Millisecond,
+	})
+	summary.Finish(2)
+
+	if summary.StatementsExecuted != 3 || summary.Succeeded != 2 || summary.Failed != 1 {
+		t.Errorf("Unexpected counts: %+v", summary)
+	}
+	if summary.RowsReturned != 2 || summary.RowsAffected != 3 {
+		t.Errorf("Expected 2 rows returned and 3 affected, got %d and %d", summary.RowsReturned, summary.RowsAffected)
+	}
+
+	if len(summary.Slowest) != 2 {
+		t.Fatalf("Expected 2 slowest statements, got %d", len(summary.Slowest))
+	}
+	if summary.Slowest[0].LineNumber != 5 || summary.Slowest[1].LineNumber != 1 {
+		t.Errorf("Expected slowest statements ordered by duration, got %+v", summary.Slowest)
+	}
+}
+
+func TestSummaryOutput(t *testing.T) {
+	summary := NewSummary()
+	summary.Record(&database.ExecutionResult{
+		Statement:  "SELECT 1",
+		FileName:   "a.sql",
+		LineNumber: 3,
+		Duration:   1500 * time.Microsecond,
+	})
+	summary.Finish(5)
+
+	var text bytes.Buffer
+	if err := summary.WriteText(&text); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{"Statements executed: 1 (1 succeeded, 0 failed)", "1.500 ms  a.sql:3  SELECT 1"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("Expected summary to contain %q, got: %s", expected, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteJSON(&buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if decoded["statements_executed"] != float64(1) {
+		t.Errorf("Expected statements_executed 1, got %v", decoded["statements_executed"])
+	}
+	slowest := decoded["slowest"].([]interface{})
+	if slowest[0].(map[string]interface{})["duration_ms"] != 1.5 {
+		t.Errorf("Expected duration_ms 1.5, got %v", slowest[0])
+	}
+}
+
+func TestSummaryThroughput(t *testing.T) {
+	summary := NewSummary()
+	summary.Record(&database.ExecutionResult{RowsAffected: 40})
+	summary.Record(&database.ExecutionResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}, {2}}})
+	summary.startTime = time.Now().Add(-2 * time.Second)
+	summary.Finish(5)
+
+	// 42 rows over a little more than two seconds
+	if summary.RowsPerSecond < 20 || summary.RowsPerSecond > 21 {
+		t.Errorf("Expected about 21 rows/s, got %f", summary.RowsPerSecond)
+	}
+
+	var text bytes.Buffer
+	if err := summary.WriteText(&text); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(text.String(), "Throughput:") || !strings.Contains(text.String(), "rows/s") {
+		t.Errorf("Expected the throughput in the summary, got: %s", text.String())
+	}
+}
+
+func TestStatementPreviewKeepsCharacters(t *testing.T) {
+	preview := statementPreview("SELECT '" + strings.Repeat("é", 80) + "'")
+	if !utf8.ValidString(preview) {
+		t.Errorf("Expected valid UTF-8, got %q", preview)
+	}
+	if n := utf8.RuneCountInString(preview); n != 60 {
+		t.Errorf("Expected a preview of 60 characters, got %d: %q", n, preview)
+	}
+}
@@ -0,0 +1,119 @@
+package output
+
+import (
+	"io"
+	"strings"
+)
+
+// DelimitedOptions configure the csv and tsv formats
+type DelimitedOptions struct {
+	// Delimiter separates fields; 0 means a comma for csv and a tab for tsv
+	Delimiter rune
+	// NoHeader leaves out the line of column names
+	NoHeader bool
+	// CRLF ends lines with \r\n instead of \n
+	CRLF bool
+	// QuoteAll quotes every field, not only those that need it
+	QuoteAll bool
+	// BOM starts the output with a UTF-8 byte order mark, which Excel needs
+	// to read non-ASCII text correctly
+	BOM bool
+}
+
+// utf8BOM is the UTF-8 encoding of the byte order mark
+const utf8BOM = "\ufeff"
+
+// SetDelimitedOptions sets the delimiter, header, line ending, quoting and
+// byte order mark of csv and tsv output
+func (f *Formatter) SetDelimitedOptions(options DelimitedOptions) {
+	f.delimited = options
+}
+
+// delimitedWriter writes records of delimited text. Fields are quoted as
+// encoding/csv does, or always when quoteAll is set.
+type delimitedWriter struct {
+	w         io.Writer
+	delimiter rune
+	lineEnd   string
+	quoteAll  bool
+	header    bool
+}
+
+// newDelimitedWriter returns a writer for records in the given format,
+// writing the byte order mark first when it is enabled and this is the
+// start of the output
+func (f *Formatter) newDelimitedWriter(format string) (*delimitedWriter, error) {
+	if f.delimited.BOM && !f.delimitedStarted {
+		if _, err := io.WriteString(f.writer, utf8BOM); err != nil {
+			return nil, err
+		}
+	}
+	f.delimitedStarted = true
+
+	delimiter := f.delimited.Delimiter
+	if delimiter == 0 {
+		delimiter = ','
+		if format == "tsv" {
+			delimiter = '\t'
+		}
+	}
+	lineEnd := "\n"
+	if f.delimited.CRLF {
+		lineEnd = "\r\n"
+	}
+	return &delimitedWriter{
+		w:         f.writer,
+		delimiter: delimiter,
+		lineEnd:   lineEnd,
+		quoteAll:  f.delimited.QuoteAll,
+		header:    !f.delimited.NoHeader,
+	}, nil
+}
+
+// WriteHeader writes the column names unless the header is turned off
+func (d *delimitedWriter) WriteHeader(columns []string) error {
+	if !d.header {
+		return nil
+	}
+	return d.Write(columns)
+}
+
+// Write writes one record
+func (d *delimitedWriter) Write(fields []string) error {
+	var b strings.Builder
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteRune(d.delimiter)
+		}
+		if d.quoteAll || d.needsQuotes(field) {
+			b.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`)
+		} else {
+			b.WriteString(field)
+		}
+	}
+	b.WriteString(d.lineEnd)
+	_, err := io.WriteString(d.w, b.String())
+	return err
+}
+
+// WriteSeparator writes the blank line between result sets. Without a
+// header the rows of all sets run on, so loaders see one block of records.
+func (d *delimitedWriter) WriteSeparator() error {
+	if !d.header {
+		return nil
+	}
+	_, err := io.WriteString(d.w, d.lineEnd)
+	return err
+}
+
+// needsQuotes reports whether a field must be quoted to be read back
+// unchanged, following the rules of encoding/csv
+func (d *delimitedWriter) needsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.ContainsRune(field, d.delimiter) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	return field[0] == ' ' || field[0] == '\t'
+}
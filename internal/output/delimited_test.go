@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/database"
+)
+
+func TestFormatTSV(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("tsv", &buf)
+	result := &database.ExecutionResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]interface{}{{int64(1), "John Doe"}, {int64(2), "tab\there"}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "id\tname\n1\tJohn Doe\n2\t\"tab\there\"\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestDelimitedOptions(t *testing.T) {
+	result := &database.ExecutionResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]interface{}{{int64(1), "a|b"}, {int64(2), nil}},
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		options  DelimitedOptions
+		expected string
+	}{
+		{"pipe delimiter", "csv", DelimitedOptions{Delimiter: '|'}, "id|name\n1|\"a|b\"\n2|\n"},
+		{"no header", "csv", DelimitedOptions{NoHeader: true}, "1,a|b\n2,\n"},
+		{"crlf", "csv", DelimitedOptions{CRLF: true}, "id,name\r\n1,a|b\r\n2,\r\n"},
+		{"quote all", "tsv", DelimitedOptions{QuoteAll: true}, "\"id\"\t\"name\"\n\"1\"\t\"a|b\"\n\"2\"\t\"\"\n"},
+		{"bom", "csv", DelimitedOptions{BOM: true}, "\ufeffid,name\n1,a|b\n2,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			formatter := NewFormatter(tt.format, &buf)
+			formatter.SetDelimitedOptions(tt.options)
+			if err := formatter.FormatResult(result); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestDelimitedBOMWrittenOnce(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("csv", &buf)
+	formatter.SetDelimitedOptions(DelimitedOptions{BOM: true})
+	result := &database.ExecutionResult{
+		Columns: []string{"id"},
+		Rows:    [][]interface{}{{int64(1)}},
+	}
+	for i := 0; i < 2; i++ {
+		if err := formatter.FormatResult(result); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if count := strings.Count(buf.String(), "\ufeff"); count != 1 {
+		t.Errorf("Expected one byte order mark, got %d in %q", count, buf.String())
+	}
+}
+
+func TestFormatDataDelimited(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("csv", &buf)
+	formatter.SetDelimitedOptions(DelimitedOptions{Delimiter: '|', NoHeader: true})
+	data := []Record{
+		{{Key: "name", Value: "main"}, {Key: "driver", Value: "sqlite3"}},
+		{{Key: "name", Value: "reporting"}, {Key: "driver", Value: "postgres"}},
+	}
+	if err := formatter.FormatData(data); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expected := "main|sqlite3\nreporting|postgres\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
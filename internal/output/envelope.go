@@ -0,0 +1,147 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gosqlpp/internal/database"
+)
+
+// envelopeDocument is the single JSON document written by the json-envelope
+// format at the end of a run
+type envelopeDocument struct {
+	Statements []envelopeStatement `json:"statements"`
+}
+
+// envelopeStatement describes one executed statement or schema command
+type envelopeStatement struct {
+	File         string                `json:"file"`
+	Line         int                   `json:"line"`
+	SQL          string                `json:"sql"`
+	Columns      []string              `json:"columns"`
+	ColumnTypes  []database.ColumnType `json:"column_types"`
+	Rows         []Record              `json:"rows"`
+	RowsAffected int64                 `json:"rows_affected"`
+	DurationMs   float64               `json:"duration_ms"`
+	Error        *string               `json:"error"`
+	// ResultSets lists every result set when a statement returned several
+	ResultSets []resultSetObject   `json:"result_sets,omitempty"`
+	Truncated  bool                `json:"truncated,omitempty"`
+	DryRun     bool                `json:"dry_run,omitempty"`
+	Skipped    bool                `json:"skipped,omitempty"`
+	Plan       *database.QueryPlan `json:"plan,omitempty"`
+}
+
+// CommandLocation is where a schema command such as @schema-tables appears
+// in a script
+type CommandLocation struct {
+	File string
+	Line int
+	SQL  string
+}
+
+// SetCommandLocation sets the location of the schema command whose output
+// follows, so its json-envelope entries say where it came from. The zero
+// value clears it.
+func (f *Formatter) SetCommandLocation(location CommandLocation) {
+	f.command = location
+}
+
+// formatNDJSON writes one compact JSON object per row. Result sets are not
+// separated, as every line stands on its own.
+func (f *Formatter) formatNDJSON(sets []database.ResultSet) error {
+	encoder := json.NewEncoder(f.writer)
+	for _, set := range sets {
+		for _, record := range resultSetRecords(set, jsonValue) {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// formatDataAsNDJSON writes generic data as one JSON object per line
+func (f *Formatter) formatDataAsNDJSON(data []Record) error {
+	encoder := json.NewEncoder(f.writer)
+	for _, record := range data {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addEnvelopeResult records an execution result for the json-envelope document
+func (f *Formatter) addEnvelopeResult(result *database.ExecutionResult) {
+	statement := envelopeStatement{
+		File:         result.FileName,
+		Line:         result.LineNumber,
+		SQL:          result.Statement,
+		Columns:      []string{},
+		ColumnTypes:  []database.ColumnType{},
+		Rows:         []Record{},
+		RowsAffected: result.RowsAffected,
+		DurationMs:   float64(result.Duration.Microseconds()) / 1000,
+		Truncated:    result.Truncated,
+		DryRun:       result.DryRun,
+		Skipped:      result.Skipped,
+		Plan:         result.Plan,
+	}
+	if statement.File == "" && statement.SQL == "" {
+		// Results built by schema commands carry no location of their own
+		statement.File, statement.Line, statement.SQL = f.command.File, f.command.Line, f.command.SQL
+	}
+	if result.Error != nil {
+		message := result.Error.Error()
+		statement.Error = &message
+	}
+
+	sets := result.GetResultSets()
+	if len(sets) > 0 {
+		statement.Columns = sets[0].Columns
+		if sets[0].ColumnTypes != nil {
+			statement.ColumnTypes = sets[0].ColumnTypes
+		}
+		statement.Rows = resultSetRecords(sets[0], jsonValue)
+	}
+	if len(sets) > 1 {
+		statement.ResultSets = resultSetObjects(sets, jsonValue)
+	}
+
+	f.envelope = append(f.envelope, statement)
+}
+
+// addEnvelopeData records generic data, such as schema command output, for
+// the json-envelope document
+func (f *Formatter) addEnvelopeData(data []Record) {
+	statement := envelopeStatement{
+		File:        f.command.File,
+		Line:        f.command.Line,
+		SQL:         f.command.SQL,
+		Columns:     []string{},
+		ColumnTypes: []database.ColumnType{},
+		Rows:        []Record{},
+	}
+	if len(data) > 0 {
+		statement.Columns, _ = dataRows(data)
+		statement.Rows = data
+	}
+	f.envelope = append(f.envelope, statement)
+}
+
+// writeEnvelope writes the json-envelope document collected during the run
+func (f *Formatter) writeEnvelope() error {
+	document := envelopeDocument{Statements: f.envelope}
+	if document.Statements == nil {
+		document.Statements = []envelopeStatement{}
+	}
+	f.envelope = nil
+
+	encoder := json.NewEncoder(f.writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("error writing JSON envelope: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gosqlpp/internal/database"
+)
+
+func TestFormatNDJSON(t *testing.T) {
+	var buf, messages bytes.Buffer
+	formatter := NewFormatter("ndjson", &buf)
+	formatter.messages = &messages
+
+	result := &database.ExecutionResult{
+		Columns: []string{"id", "name"},
+		Rows:    [][]interface{}{{int64(1), "john"}, {int64(2), "jane"}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := formatter.FormatResult(&database.ExecutionResult{RowsAffected: 1}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "{\"id\":1,\"name\":\"john\"}\n{\"id\":2,\"name\":\"jane\"}\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected ndjson output:\n%s", buf.String())
+	}
+	if messages.Len() == 0 {
+		t.Error("Expected status messages to go to the message writer")
+	}
+}
+
+func TestFormatJSONEnvelope(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("json-envelope", &buf)
+
+	results := []*database.ExecutionResult{
+		{
+			FileName:   "report.sql",
+			LineNumber: 1,
+			Statement:  "SELECT 1 AS n",
+			Columns:    []string{"n"},
+			Rows:       [][]interface{}{{int64(1)}},
+			Duration:   1500 * time.Microsecond,
+		},
+		{
+			FileName:     "report.sql",
+			LineNumber:   3,
+			Statement:    "DELETE FROM t",
+			RowsAffected: 4,
+		},
+		{
+			FileName:   "report.sql",
+			LineNumber: 5,
+			Statement:  "SELEC 1",
+			Error:      errors.New("syntax error"),
+		},
+	}
+	for _, result := range results {
+		if err := formatter.FormatResult(result); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Expected nothing to be written before Close, got: %s", buf.String())
+	}
+	if err := formatter.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var document struct {
+		Statements []struct {
+			File         string                   `json:"file"`
+			Line         int                      `json:"line"`
+			SQL          string                   `json:"sql"`
+			Columns      []string                 `json:"columns"`
+			Rows         []map[string]interface{} `json:"rows"`
+			RowsAffected int64                    `json:"rows_affected"`
+			DurationMs   float64                  `json:"duration_ms"`
+			Error        *string                  `json:"error"`
+		} `json:"statements"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("Expected a single valid JSON document, got %v: %s", err, buf.String())
+	}
+	if len(document.Statements) != 3 {
+		t.Fatalf("Expected 3 statements, got %d", len(document.Statements))
+	}
+
+	first := document.Statements[0]
+	if first.SQL != "SELECT 1 AS n" || first.Line != 1 || first.DurationMs != 1.5 || len(first.Rows) != 1 || first.Rows[0]["n"] != float64(1) {
+		t.Errorf("Unexpected first statement: %+v", first)
+	}
+	if document.Statements[1].RowsAffected != 4 || document.Statements[1].Rows == nil {
+		t.Errorf("Unexpected second statement: %+v", document.Statements[1])
+	}
+	if document.Statements[2].Error == nil || *document.Statements[2].Error != "syntax error" {
+		t.Errorf("Expected the error to be recorded, got: %+v", document.Statements[2])
+	}
+	if !strings.Contains(buf.String(), `"error": null`) {
+		t.Errorf("Expected successful statements to have a null error, got: %s", buf.String())
+	}
+}
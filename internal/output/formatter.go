@@ -1,16 +1,20 @@
 package output
 
 import (
-	"encoding/csv"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"gosqlpp/internal/config"
 	"gosqlpp/internal/database"
 
-	"github.com/rodaine/table"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,145 +22,289 @@ import (
 type Formatter struct {
 	format string
 	writer io.Writer
+	// width overrides the terminal width used by the auto format
+	width int
+	// htmlDocument wraps html output in a document styled with htmlCSS;
+	// documentStarted records that its header has been written
+	htmlDocument    bool
+	htmlCSS         string
+	documentStarted bool
+	// insertTable, insertDialect and insertBatchSize configure sql-insert output
+	insertTable     string
+	insertDialect   string
+	insertBatchSize int
+	// messages receives status messages for formats whose output must stay
+	// machine-readable, such as ndjson
+	messages io.Writer
+	// envelope collects the statements of a run for the json-envelope format;
+	// command is the location of the schema command being run, for the
+	// entries it adds
+	envelope []envelopeStatement
+	command  CommandLocation
+	// template renders the template format; templates caches the files
+	// named by @template hints
+	template  *template.Template
+	templates map[string]*template.Template
+	// file is the output file opened by SetOutputFile; spool is the file
+	// opened by Spool and spooled the output it replaced
+	file    *os.File
+	spool   *os.File
+	spooled *savedOutput
+	// outputDir and outputName make each statement's rows go to a file of
+	// its own; outputCount counts the files written and outputFiles holds
+	// their paths
+	outputDir   string
+	outputName  string
+	outputCount int
+	outputFiles map[string]bool
+	// sheets collects the worksheets of the xlsx format; sheetStatements
+	// counts the statements that added sheets
+	sheets          []xlsxSheet
+	sheetStatements int
+	// values controls how values are rendered by the text formats
+	values ValueOptions
+	// delimited configures csv and tsv output; delimitedStarted records
+	// that delimited output, and so its byte order mark, has been written
+	delimited        DelimitedOptions
+	delimitedStarted bool
+	// maxColWidth and wrap limit the width of table columns; color is
+	// when tables and errors are colored: always, never or auto
+	maxColWidth int
+	wrap        bool
+	color       string
 }
 
 // NewFormatter creates a new output formatter
 func NewFormatter(format string, writer io.Writer) *Formatter {
 	return &Formatter{
-		format: format,
-		writer: writer,
+		format:   format,
+		writer:   writer,
+		messages: os.Stderr,
 	}
 }
 
 // FormatResult formats and outputs the execution result
 func (f *Formatter) FormatResult(result *database.ExecutionResult) error {
+	if f.writesOwnFile(result) {
+		return f.formatResultToFile(result)
+	}
+	return f.formatResult(result)
+}
+
+// resultFormat returns the format a result is written in: the requested
+// format, which a statement can override with an @output or @template hint
+func (f *Formatter) resultFormat(result *database.ExecutionResult) string {
+	hints := database.ParseHints(result.Statement)
+	if hints.Has("template") {
+		return "template"
+	}
+	if hint := hints["output"]; hint != "" {
+		return strings.ToLower(hint)
+	}
+	return f.format
+}
+
+// formatResult writes the execution result to the current output
+func (f *Formatter) formatResult(result *database.ExecutionResult) error {
+	// The envelope is written as a single document when the run ends
+	if f.format == "json-envelope" {
+		f.addEnvelopeResult(result)
+		return nil
+	}
+
+	// Validated statements only report whether they are valid
+	if result.DryRun {
+		return f.writeMessage(f.format, database.FormatValidation(result))
+	}
+
 	if result.Error != nil {
-		// Always output errors as plain text
-		_, err := fmt.Fprintf(f.writer, "%s\n", database.FormatError(result))
-		return err
+		// Always output errors as plain text, in red in a terminal
+		message := database.FormatError(result)
+		if f.useColor(f.format) {
+			message = ansiRed + message + ansiReset
+		}
+		return f.writeMessage(f.format, message)
+	}
+
+	// Explained statements show their query plan instead of rows
+	if result.Plan != nil {
+		return f.formatPlan(result)
+	}
+
+	// Templates also render statements that return no rows
+	format := f.resultFormat(result)
+	sets := result.GetResultSets()
+	if format == "template" {
+		return f.formatTemplate(result, sets)
 	}
 
 	// If no rows returned, just show the affected rows message
-	if len(result.Rows) == 0 {
+	if result.TotalRows() == 0 {
 		message := database.FormatRowsAffected(result)
 		if message != "" {
-			_, err := fmt.Fprintf(f.writer, "%s\n", message)
-			return err
+			return f.writeMessage(f.format, message)
 		}
 		return nil
 	}
 
-	// Format the result data based on the requested format
-	switch f.format {
+	var err error
+	switch format {
 	case "table":
-		return f.formatTable(result)
+		err = f.formatTable(sets)
+	case "vertical":
+		err = f.formatVertical(sets)
+	case "auto":
+		err = f.formatAuto(sets)
 	case "json":
-		return f.formatJSON(result)
+		err = f.formatJSON(sets)
 	case "yaml":
-		return f.formatYAML(result)
-	case "csv":
-		return f.formatCSV(result)
+		err = f.formatYAML(sets)
+	case "csv", "tsv":
+		err = f.formatCSV(format, sets)
+	case "markdown", "html", "asciidoc":
+		err = f.formatMarkup(format, sets)
+	case "sql-insert":
+		err = f.formatInsert(result, sets)
+	case "ndjson":
+		err = f.formatNDJSON(sets)
+	case "xlsx":
+		f.addSheets(result, sets)
 	default:
-		return fmt.Errorf("unsupported output format: %s", f.format)
+		return fmt.Errorf("unsupported output format: %s", format)
 	}
-}
-
-// formatTable formats the result as a table
-func (f *Formatter) formatTable(result *database.ExecutionResult) error {
-	if len(result.Rows) == 0 {
-		return nil
+	if err != nil {
+		return err
 	}
 
-	// Convert column names to interface{} slice
-	headers := make([]interface{}, len(result.Columns))
-	for i, col := range result.Columns {
-		headers[i] = col
+	// Point out that rows were left out because of the row limit
+	if result.Truncated {
+		err = f.writeMessage(format, database.FormatTruncation(result))
 	}
+	return err
+}
 
-	// Create table with headers
-	tbl := table.New(headers...)
-	tbl.WithWriter(f.writer)
+// formatTable formats each result set as a separate table
+func (f *Formatter) formatTable(sets []database.ResultSet) error {
+	for i, set := range sets {
+		if len(sets) > 1 {
+			if i > 0 {
+				fmt.Fprintln(f.writer)
+			}
+			fmt.Fprintf(f.writer, "Result set %d:\n", i+1)
+		}
 
-	// Add rows
-	for _, row := range result.Rows {
-		// Convert all values to strings for table display
-		stringRow := make([]interface{}, len(row))
-		for i, val := range row {
-			stringRow[i] = formatValue(val)
+		if len(set.Rows) > 0 {
+			f.writeTable(set.Columns, set.Rows)
+			fmt.Fprintln(f.writer)
 		}
-		tbl.AddRow(stringRow...)
+
+		// Add row count
+		fmt.Fprintf(f.writer, "(%d rows)\n", set.RowCount)
 	}
 
-	tbl.Print()
+	return nil
+}
+
+// formatJSON formats the result as JSON. A single result set is written as
+// an array of row objects; multiple result sets are written as an array of
+// result objects, each holding its own columns and rows.
+func (f *Formatter) formatJSON(sets []database.ResultSet) error {
+	encoder := json.NewEncoder(f.writer)
+	encoder.SetIndent("", "  ")
+
+	if len(sets) == 1 {
+		return encoder.Encode(resultSetRecords(sets[0], jsonValue))
+	}
+	return encoder.Encode(resultSetObjects(sets, jsonValue))
+}
 
-	// Add row count
-	fmt.Fprintf(f.writer, "\n%s\n", database.FormatRowsAffected(result))
+// formatYAML formats the result as YAML, one document per result set
+func (f *Formatter) formatYAML(sets []database.ResultSet) error {
+	encoder := yaml.NewEncoder(f.writer)
+	defer encoder.Close()
 
+	for _, set := range sets {
+		if err := encoder.Encode(resultSetRecords(set, yamlValue)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// formatJSON formats the result as JSON
-func (f *Formatter) formatJSON(result *database.ExecutionResult) error {
-	// Convert rows to array of objects
-	var records []map[string]interface{}
+// formatCSV formats the result as CSV or TSV, separating result sets with a
+// blank line
+func (f *Formatter) formatCSV(format string, sets []database.ResultSet) error {
+	writer, err := f.newDelimitedWriter(format)
+	if err != nil {
+		return err
+	}
 
-	for _, row := range result.Rows {
-		record := make(map[string]interface{})
-		for i, col := range result.Columns {
-			if i < len(row) {
-				record[col] = row[i]
+	for i, set := range sets {
+		if i > 0 {
+			if err := writer.WriteSeparator(); err != nil {
+				return err
+			}
+		}
+
+		// Write header
+		if err := writer.WriteHeader(set.Columns); err != nil {
+			return err
+		}
+
+		// Write rows
+		for _, row := range set.Rows {
+			stringRow := make([]string, len(row))
+			for j, val := range row {
+				stringRow[j] = f.formatCSVValue(val)
+			}
+			if err := writer.Write(stringRow); err != nil {
+				return err
 			}
 		}
-		records = append(records, record)
 	}
 
-	encoder := json.NewEncoder(f.writer)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(records)
+	return nil
 }
 
-// formatYAML formats the result as YAML
-func (f *Formatter) formatYAML(result *database.ExecutionResult) error {
-	// Convert rows to array of objects
-	var records []map[string]interface{}
-
-	for _, row := range result.Rows {
-		record := make(map[string]interface{})
-		for i, col := range result.Columns {
+// resultSetRecords converts the rows of a result set to an array of records
+// in column order, converting each value with convert
+func resultSetRecords(set database.ResultSet, convert func(interface{}) interface{}) []Record {
+	records := make([]Record, 0, len(set.Rows))
+	for _, row := range set.Rows {
+		record := make(Record, 0, len(set.Columns))
+		for i, col := range set.Columns {
 			if i < len(row) {
-				record[col] = row[i]
+				record.Set(col, convert(row[i]))
 			}
 		}
 		records = append(records, record)
 	}
 
-	encoder := yaml.NewEncoder(f.writer)
-	defer encoder.Close()
-	return encoder.Encode(records)
+	return records
 }
 
-// formatCSV formats the result as CSV
-func (f *Formatter) formatCSV(result *database.ExecutionResult) error {
-	writer := csv.NewWriter(f.writer)
-	defer writer.Flush()
-
-	// Write header
-	if err := writer.Write(result.Columns); err != nil {
-		return err
-	}
+// resultSetObject is a result set written as a JSON object
+type resultSetObject struct {
+	Columns     []string              `json:"columns"`
+	ColumnTypes []database.ColumnType `json:"column_types,omitempty"`
+	Rows        []Record              `json:"rows"`
+	RowCount    int64                 `json:"row_count"`
+	Truncated   bool                  `json:"truncated,omitempty"`
+}
 
-	// Write rows
-	for _, row := range result.Rows {
-		stringRow := make([]string, len(row))
-		for i, val := range row {
-			stringRow[i] = formatValue(val)
-		}
-		if err := writer.Write(stringRow); err != nil {
-			return err
-		}
+// resultSetObjects converts result sets to objects holding columns, rows and row count
+func resultSetObjects(sets []database.ResultSet, convert func(interface{}) interface{}) []resultSetObject {
+	objects := make([]resultSetObject, 0, len(sets))
+	for _, set := range sets {
+		objects = append(objects, resultSetObject{
+			Columns:     set.Columns,
+			ColumnTypes: set.ColumnTypes,
+			Rows:        resultSetRecords(set, convert),
+			RowCount:    set.RowCount,
+			Truncated:   set.Truncated,
+		})
 	}
-
-	return nil
+	return objects
 }
 
 // formatValue converts a database value to a string representation
@@ -168,8 +316,14 @@ func formatValue(val interface{}) string {
 	switch v := val.(type) {
 	case string:
 		return v
+	case database.Decimal:
+		return string(v)
+	case database.Binary:
+		return "0x" + hex.EncodeToString(v)
 	case []byte:
 		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
 	case int, int8, int16, int32, int64:
 		return fmt.Sprintf("%d", v)
 	case uint, uint8, uint16, uint32, uint64:
@@ -183,9 +337,51 @@ func formatValue(val interface{}) string {
 	}
 }
 
+// jsonValue converts a database value to a value that encodes faithfully as
+// JSON: decimals become JSON numbers without float rounding, binary data is
+// base64 encoded and times use RFC 3339
+func jsonValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case database.Decimal:
+		if isJSONNumber(string(v)) {
+			return json.Number(v)
+		}
+		return string(v)
+	case database.Binary:
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return val
+	}
+}
+
+// yamlValue converts a database value to a value that encodes faithfully as
+// YAML, following the same rules as jsonValue
+func yamlValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case database.Decimal:
+		if isJSONNumber(string(v)) {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: string(v)}
+		}
+		return string(v)
+	case database.Binary:
+		return base64.StdEncoding.EncodeToString(v)
+	default:
+		return val
+	}
+}
+
+// isJSONNumber reports whether s is a valid JSON number literal
+func isJSONNumber(s string) bool {
+	return s != "" && json.Valid([]byte(s)) && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9') && r != '-' && r != '+' && r != '.' && r != 'e' && r != 'E'
+	}) < 0
+}
+
 // GetSupportedFormats returns a list of supported output formats
 func GetSupportedFormats() []string {
-	return []string{"table", "json", "yaml", "csv"}
+	return append([]string(nil), config.OutputFormats...)
 }
 
 // IsFormatSupported checks if the given format is supported
@@ -198,98 +394,91 @@ func IsFormatSupported(format string) bool {
 	return false
 }
 
-// FormatData formats generic data (slice of maps) using the specified format
-func (f *Formatter) FormatData(data []map[string]interface{}) error {
+// FormatData formats generic data (slice of records) using the specified format
+func (f *Formatter) FormatData(data []Record) error {
+	if f.format == "json-envelope" {
+		f.addEnvelopeData(data)
+		return nil
+	}
 	if len(data) == 0 {
-		_, err := fmt.Fprintf(f.writer, "No data to display\n")
-		return err
+		return f.writeMessage(f.format, "No data to display")
 	}
 
 	switch f.format {
 	case "table":
 		return f.formatDataAsTable(data)
+	case "vertical":
+		return f.formatDataAsVertical(data)
+	case "auto":
+		return f.formatDataAsAuto(data)
 	case "json":
 		return f.formatDataAsJSON(data)
 	case "yaml":
 		return f.formatDataAsYAML(data)
-	case "csv":
+	case "csv", "tsv":
 		return f.formatDataAsCSV(data)
+	case "markdown", "html", "asciidoc":
+		return f.formatDataAsMarkup(data)
+	case "sql-insert":
+		return f.formatDataAsInsert(data)
+	case "ndjson":
+		return f.formatDataAsNDJSON(data)
+	case "template":
+		return f.formatDataAsTemplate(data)
+	case "xlsx":
+		return f.formatDataAsXLSX(data)
 	default:
 		return fmt.Errorf("unsupported output format: %s", f.format)
 	}
 }
 
 // formatDataAsTable formats generic data as a table
-func (f *Formatter) formatDataAsTable(data []map[string]interface{}) error {
+func (f *Formatter) formatDataAsTable(data []Record) error {
 	if len(data) == 0 {
 		return nil
 	}
 
-	// Extract headers from the first record
-	var headers []interface{}
-	for key := range data[0] {
-		headers = append(headers, key)
-	}
-
-	// Create table with headers
-	tbl := table.New(headers...)
-	tbl.WithWriter(f.writer)
-
-	// Add rows
-	for _, record := range data {
-		row := make([]interface{}, len(headers))
-		for i, header := range headers {
-			row[i] = formatValue(record[header.(string)])
-		}
-		tbl.AddRow(row...)
-	}
-
-	tbl.Print()
+	f.writeTable(dataRows(data))
 	return nil
 }
 
 // formatDataAsJSON formats generic data as JSON
-func (f *Formatter) formatDataAsJSON(data []map[string]interface{}) error {
+func (f *Formatter) formatDataAsJSON(data []Record) error {
 	encoder := json.NewEncoder(f.writer)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(data)
 }
 
 // formatDataAsYAML formats generic data as YAML
-func (f *Formatter) formatDataAsYAML(data []map[string]interface{}) error {
+func (f *Formatter) formatDataAsYAML(data []Record) error {
 	encoder := yaml.NewEncoder(f.writer)
 	defer encoder.Close()
 	return encoder.Encode(data)
 }
 
 // formatDataAsCSV formats generic data as CSV
-func (f *Formatter) formatDataAsCSV(data []map[string]interface{}) error {
+func (f *Formatter) formatDataAsCSV(data []Record) error {
 	if len(data) == 0 {
 		return nil
 	}
 
-	writer := csv.NewWriter(f.writer)
-	defer writer.Flush()
-
-	// Extract headers from the first record
-	var headers []string
-	for key := range data[0] {
-		headers = append(headers, key)
+	writer, err := f.newDelimitedWriter(f.format)
+	if err != nil {
+		return err
 	}
 
+	columns, rows := dataRows(data)
+
 	// Write headers
-	if err := writer.Write(headers); err != nil {
+	if err := writer.WriteHeader(columns); err != nil {
 		return err
 	}
 
 	// Write data rows
-	for _, record := range data {
-		row := make([]string, len(headers))
-		for i, header := range headers {
-			value := record[header]
-			if value != nil {
-				row[i] = fmt.Sprintf("%v", value)
-			}
+	for _, values := range rows {
+		row := make([]string, len(values))
+		for i, value := range values {
+			row[i] = f.formatCSVValue(value)
 		}
 		if err := writer.Write(row); err != nil {
 			return err
@@ -2,16 +2,18 @@ package output
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"gosqlpp/internal/database"
 )
 
 func TestGetSupportedFormats(t *testing.T) {
 	formats := GetSupportedFormats()
-	expected := []string{"table", "json", "yaml", "csv"}
+	expected := []string{"table", "json", "yaml", "csv", "tsv", "vertical", "auto", "markdown", "html", "asciidoc", "sql-insert", "ndjson", "json-envelope", "template", "xlsx"}
 	
 	if len(formats) != len(expected) {
 		t.Errorf("Expected %d formats, got %d", len(expected), len(formats))
@@ -155,3 +157,367 @@ func TestFormatError(t *testing.T) {
 		t.Errorf("Expected error format, got: %s", output)
 	}
 }
+
+func multipleResultSets() *database.ExecutionResult {
+	return &database.ExecutionResult{
+		ResultSets: []database.ResultSet{
+			{Columns: []string{"id"}, Rows: [][]interface{}{{1}, {2}}, RowCount: 2},
+			{Columns: []string{"name"}, Rows: [][]interface{}{{"John"}}, RowCount: 1},
+		},
+	}
+}
+
+func TestFormatMultipleResultSetsTable(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+
+	if err := formatter.FormatResult(multipleResultSets()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"Result set 1:", "Result set 2:", "(2 rows)", "(1 rows)", "John"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got: %s", expected, output)
+		}
+	}
+}
+
+func TestFormatMultipleResultSetsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("json", &buf)
+
+	if err := formatter.FormatResult(multipleResultSets()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var sets []struct {
+		Columns  []string                 `json:"columns"`
+		Rows     []map[string]interface{} `json:"rows"`
+		RowCount int                      `json:"row_count"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &sets); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, buf.String())
+	}
+	if len(sets) != 2 {
+		t.Fatalf("Expected 2 result objects, got %d", len(sets))
+	}
+	if sets[1].Columns[0] != "name" || sets[1].Rows[0]["name"] != "John" || sets[1].RowCount != 1 {
+		t.Errorf("Unexpected second result set: %+v", sets[1])
+	}
+}
+
+func TestFormatMultipleResultSetsYAML(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("yaml", &buf)
+
+	if err := formatter.FormatResult(multipleResultSets()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if documents := strings.Count(buf.String(), "---"); documents != 1 {
+		t.Errorf("Expected 2 YAML documents separated by '---', got: %s", buf.String())
+	}
+}
+
+func typedResult() *database.ExecutionResult {
+	return &database.ExecutionResult{
+		Columns: []string{"amount", "data", "created", "active"},
+		Rows: [][]interface{}{
+			{database.Decimal("12345678901234567.89"), database.Binary{0x00, 0xff}, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), true},
+		},
+	}
+}
+
+func TestFormatJSONTypedValues(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("json", &buf)
+
+	if err := formatter.FormatResult(typedResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		`"amount": 12345678901234567.89`,
+		`"data": "AP8="`,
+		`"created": "2024-03-01T12:30:00Z"`,
+		`"active": true`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %s, got: %s", expected, output)
+		}
+	}
+}
+
+func TestFormatYAMLTypedValues(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("yaml", &buf)
+
+	if err := formatter.FormatResult(typedResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"amount: 12345678901234567.89", "data: AP8=", "active: true"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %s, got: %s", expected, output)
+		}
+	}
+}
+
+func TestFormatValueTypedValues(t *testing.T) {
+	if result := formatValue(database.Decimal("1.10")); result != "1.10" {
+		t.Errorf("Expected decimal '1.10', got %s", result)
+	}
+	if result := formatValue(database.Binary{0xde, 0xad}); result != "0xdead" {
+		t.Errorf("Expected binary '0xdead', got %s", result)
+	}
+	if result := formatValue(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)); result != "2024-03-01T00:00:00Z" {
+		t.Errorf("Expected RFC 3339 time, got %s", result)
+	}
+}
+
+func TestFormatDryRunResult(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("json", &buf)
+
+	result := &database.ExecutionResult{
+		DryRun:     true,
+		FileName:   "deploy.sql",
+		LineNumber: 4,
+		Columns:    []string{"id"},
+		Rows:       [][]interface{}{{1}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.String() != "deploy.sql:4: ok\n" {
+		t.Errorf("Expected validation line only, got: %s", buf.String())
+	}
+}
+
+func planResult() *database.ExecutionResult {
+	return &database.ExecutionResult{
+		FileName:   "report.sql",
+		LineNumber: 7,
+		Statement:  "SELECT * FROM users",
+		Plan: &database.QueryPlan{
+			Driver: "sqlite3",
+			Nodes: []*database.PlanNode{
+				{Operation: "SCAN users", Children: []*database.PlanNode{
+					{Operation: "USE INDEX", Properties: map[string]string{"index": "idx_name"}},
+				}},
+				{Operation: "USE TEMP B-TREE FOR ORDER BY"},
+			},
+		},
+	}
+}
+
+func TestFormatPlanTree(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+
+	if err := formatter.FormatResult(planResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "Query plan for report.sql:7 (sqlite3)\n" +
+		"├── SCAN users\n" +
+		"│   └── USE INDEX (index=idx_name)\n" +
+		"└── USE TEMP B-TREE FOR ORDER BY\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected plan tree:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFormatPlanJSON(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("json", &buf)
+
+	if err := formatter.FormatResult(planResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var document struct {
+		File string `json:"file"`
+		Line int    `json:"line"`
+		Plan []struct {
+			Operation string `json:"operation"`
+			Children  []struct {
+				Operation string `json:"operation"`
+			} `json:"children"`
+		} `json:"plan"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if document.File != "report.sql" || document.Line != 7 || len(document.Plan) != 2 {
+		t.Errorf("Unexpected plan document: %+v", document)
+	}
+	if document.Plan[0].Children[0].Operation != "USE INDEX" {
+		t.Errorf("Expected nested operation, got %+v", document.Plan[0])
+	}
+}
+
+func TestFormatPlanNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("ndjson", &buf)
+
+	if err := formatter.FormatResult(planResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("Expected the plan on a single line, got: %s", buf.String())
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if document["line"] != 7.0 || document["driver"] != "sqlite3" {
+		t.Errorf("Unexpected plan document: %v", document)
+	}
+}
+
+func TestFormatPlanTemplate(t *testing.T) {
+	var buf, messages bytes.Buffer
+	formatter := NewFormatter("template", &buf)
+	formatter.messages = &messages
+
+	if err := formatter.FormatResult(planResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if messages.Len() != 0 || !strings.HasPrefix(buf.String(), "Query plan for report.sql:7") {
+		t.Errorf("Expected the plan on the output writer, got output %q and messages %q", buf.String(), messages.String())
+	}
+}
+
+func TestFormatPlanCSV(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("csv", &buf)
+
+	if err := formatter.FormatResult(planResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[2] != "2,1,1,USE INDEX,index=idx_name" {
+		t.Errorf("Unexpected plan CSV: %s", buf.String())
+	}
+}
+
+func TestFormatTruncatedResult(t *testing.T) {
+	for _, format := range []string{"table", "json", "csv"} {
+		var buf bytes.Buffer
+		formatter := NewFormatter(format, &buf)
+
+		result := &database.ExecutionResult{
+			Columns:   []string{"id"},
+			Rows:      [][]interface{}{{int64(1)}, {int64(2)}},
+			MaxRows:   2,
+			Truncated: true,
+		}
+		if err := formatter.FormatResult(result); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.HasSuffix(buf.String(), "(truncated at 2 rows)\n") {
+			t.Errorf("Expected %s output to end with the truncation footer, got: %s", format, buf.String())
+		}
+	}
+}
+
+func TestFormatVertical(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("vertical", &buf)
+
+	result := &database.ExecutionResult{
+		Columns: []string{"id", "username", "bio"},
+		Rows: [][]interface{}{
+			{int64(1), "john", "first line\nsecond line"},
+			{int64(2), "jane", nil},
+		},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := `-[ RECORD 1 ]+------------
+id       | 1
+username | john
+bio      | first line
+         | second line
+-[ RECORD 2 ]+-----
+id       | 2
+username | jane
+bio      | NULL
+
+(2 rows)
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected vertical output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFormatOutputHint(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+
+	result := &database.ExecutionResult{
+		Statement: "-- @output vertical\nSELECT 1 AS id",
+		Columns:   []string{"id"},
+		Rows:      [][]interface{}{{int64(1)}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "-[ RECORD 1 ]") {
+		t.Errorf("Expected the @output hint to select the vertical format, got: %s", buf.String())
+	}
+}
+
+func TestFormatAuto(t *testing.T) {
+	result := &database.ExecutionResult{
+		Columns: []string{"id", "description"},
+		Rows:    [][]interface{}{{int64(1), strings.Repeat("x", 50)}},
+	}
+
+	var wide bytes.Buffer
+	formatter := NewFormatter("auto", &wide)
+	formatter.SetWidth(120)
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(wide.String(), "RECORD") {
+		t.Errorf("Expected a table when it fits, got: %s", wide.String())
+	}
+
+	var narrow bytes.Buffer
+	formatter = NewFormatter("auto", &narrow)
+	formatter.SetWidth(40)
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(narrow.String(), "-[ RECORD 1 ]") {
+		t.Errorf("Expected vertical output when the table is too wide, got: %s", narrow.String())
+	}
+}
+
+func TestFormatJSONSingleResultSet(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("json", &buf)
+
+	result := &database.ExecutionResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}}}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A single result set stays a plain array of rows, like FormatData
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+		t.Fatalf("Expected an array of rows, got %v: %s", err, buf.String())
+	}
+	if len(rows) != 1 || rows[0]["id"] != 1.0 {
+		t.Errorf("Unexpected rows: %s", buf.String())
+	}
+}
@@ -0,0 +1,248 @@
+package output
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gosqlpp/internal/database"
+)
+
+// SetInsertOptions configures the sql-insert format: the target table, the
+// driver whose SQL dialect is used for quoting, and how many rows each
+// INSERT statement holds (1 or less for one statement per row)
+func (f *Formatter) SetInsertOptions(table, dialect string, batchSize int) {
+	f.insertTable = table
+	f.insertDialect = dialect
+	f.insertBatchSize = batchSize
+}
+
+// formatInsert renders result sets as INSERT statements. The target table
+// comes from the statement's @insert-into hint or from SetInsertOptions.
+func (f *Formatter) formatInsert(result *database.ExecutionResult, sets []database.ResultSet) error {
+	table := f.insertTable
+	if hint := database.ParseHints(result.Statement)["insert-into"]; hint != "" {
+		table = hint
+	}
+	if table == "" {
+		return fmt.Errorf("sql-insert output needs a target table: use --insert-table or a -- @insert-into hint")
+	}
+
+	for _, set := range sets {
+		if err := f.writeInserts(table, set.Columns, set.ColumnTypes, set.Rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeInserts writes INSERT statements for rows, batching them into
+// multi-row VALUES lists when a batch size is set. The column types, which
+// may be missing, pick the literal form of date/time values.
+func (f *Formatter) writeInserts(table string, columns []string, types []database.ColumnType, rows [][]interface{}) error {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdentifier(f.insertDialect, col)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES", quoteTableName(f.insertDialect, table), strings.Join(quoted, ", "))
+
+	batchSize := max(f.insertBatchSize, 1)
+	for start := 0; start < len(rows); start += batchSize {
+		end := min(start+batchSize, len(rows))
+
+		var b strings.Builder
+		b.WriteString(prefix)
+		for i, row := range rows[start:end] {
+			values := make([]string, len(columns))
+			for j := range columns {
+				values[j] = sqlLiteral(f.insertDialect, databaseType(types, j), rowValue(row, j))
+			}
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if end-start > 1 {
+				b.WriteString("\n  ")
+			} else {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(&b, "(%s)", strings.Join(values, ", "))
+		}
+		b.WriteString(";\n")
+
+		if _, err := fmt.Fprint(f.writer, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatDataAsInsert formats generic data as INSERT statements
+func (f *Formatter) formatDataAsInsert(data []Record) error {
+	if f.insertTable == "" {
+		return fmt.Errorf("sql-insert output needs a target table: use --insert-table")
+	}
+	columns, rows := dataRows(data)
+	return f.writeInserts(f.insertTable, columns, nil, rows)
+}
+
+// databaseType returns the database type name of column i, or "" when the
+// column types are not known
+func databaseType(types []database.ColumnType, i int) string {
+	if i < len(types) {
+		return types[i].DatabaseType
+	}
+	return ""
+}
+
+// quoteTableName quotes each part of a possibly schema-qualified table name
+func quoteTableName(dialect, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(dialect, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteIdentifier quotes an identifier for the dialect, leaving identifiers
+// that are already quoted alone
+func quoteIdentifier(dialect, name string) string {
+	switch dialect {
+	case "mysql":
+		if strings.HasPrefix(name, "`") {
+			return name
+		}
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case "sqlserver":
+		if strings.HasPrefix(name, "[") {
+			return name
+		}
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		if strings.HasPrefix(name, `"`) {
+			return name
+		}
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// sqlLiteral renders a value of a column with the given database type as a
+// SQL literal in the dialect
+func sqlLiteral(dialect, databaseType string, val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if dialect == "sqlite3" || dialect == "sqlserver" {
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32:
+		return floatLiteral(dialect, float64(v))
+	case float64:
+		return floatLiteral(dialect, v)
+	case database.Decimal:
+		if isJSONNumber(string(v)) {
+			return string(v)
+		}
+		return stringLiteral(dialect, string(v))
+	case database.Binary:
+		return binaryLiteral(dialect, v)
+	case []byte:
+		return binaryLiteral(dialect, v)
+	case time.Time:
+		return stringLiteral(dialect, v.Format(timeLiteralLayout(dialect, databaseType)))
+	default:
+		return stringLiteral(dialect, formatValue(v))
+	}
+}
+
+// floatLiteral renders a float, quoting values that have no numeric literal
+func floatLiteral(dialect string, v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return stringLiteral(dialect, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// stringLiteral quotes a string for the dialect. MySQL also treats
+// backslashes as escapes, and SQL Server prefixes Unicode text with N.
+func stringLiteral(dialect, s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	switch dialect {
+	case "mysql":
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		return "'" + s + "'"
+	case "sqlserver":
+		return "N'" + s + "'"
+	default:
+		return "'" + s + "'"
+	}
+}
+
+// binaryLiteral renders binary data as a hex literal for the dialect
+func binaryLiteral(dialect string, b []byte) string {
+	encoded := hex.EncodeToString(b)
+	switch dialect {
+	case "postgres":
+		return `'\x` + encoded + `'::bytea`
+	case "sqlserver":
+		return "0x" + encoded
+	default:
+		return "X'" + encoded + "'"
+	}
+}
+
+// timeLiteralLayout is the date/time text format each dialect reads back for
+// a column's database type. DATE and TIME columns keep only their part of
+// the value, and timestamps without a time zone are written without an
+// offset. SQL Server's DATETIME only takes milliseconds, so the seven
+// fractional digits of DATETIME2 and DATETIMEOFFSET are only used for those
+// types.
+func timeLiteralLayout(dialect, databaseType string) string {
+	databaseType = strings.ToUpper(databaseType)
+	switch databaseType {
+	case "DATE":
+		return "2006-01-02"
+	case "TIME":
+		return "15:04:05.999999"
+	case "TIMETZ":
+		if dialect == "mysql" {
+			return "15:04:05.999999"
+		}
+		return "15:04:05.999999-07:00"
+	}
+
+	switch dialect {
+	case "mysql":
+		return "2006-01-02 15:04:05.999999"
+	case "sqlserver":
+		switch databaseType {
+		case "DATETIME2":
+			return "2006-01-02T15:04:05.9999999"
+		case "DATETIMEOFFSET":
+			return "2006-01-02T15:04:05.9999999-07:00"
+		}
+		return "2006-01-02T15:04:05.999"
+	default:
+		if localTimestampTypes[databaseType] {
+			return "2006-01-02 15:04:05.999999999"
+		}
+		return "2006-01-02 15:04:05.999999999-07:00"
+	}
+}
+
+// localTimestampTypes are the timestamp types that hold no time zone
+var localTimestampTypes = map[string]bool{
+	"TIMESTAMP":     true,
+	"DATETIME":      true,
+	"DATETIME2":     true,
+	"SMALLDATETIME": true,
+}
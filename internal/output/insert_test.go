@@ -0,0 +1,170 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gosqlpp/internal/database"
+)
+
+func insertResult() *database.ExecutionResult {
+	return &database.ExecutionResult{
+		Statement: "SELECT * FROM countries",
+		Columns:   []string{"code", "name", "active", "rate", "flag"},
+		Rows: [][]interface{}{
+			{int64(1), "Côte d'Ivoire", true, database.Decimal("1.50"), database.Binary{0xca, 0xfe}},
+			{int64(2), `back\slash`, false, nil, nil},
+		},
+	}
+}
+
+func TestFormatSQLInsert(t *testing.T) {
+	tests := []struct {
+		dialect  string
+		expected string
+	}{
+		{
+			dialect: "sqlite3",
+			expected: `INSERT INTO "ref"."countries" ("code", "name", "active", "rate", "flag") VALUES (1, 'Côte d''Ivoire', 1, 1.50, X'cafe');
+INSERT INTO "ref"."countries" ("code", "name", "active", "rate", "flag") VALUES (2, 'back\slash', 0, NULL, NULL);
+`,
+		},
+		{
+			dialect: "postgres",
+			expected: `INSERT INTO "ref"."countries" ("code", "name", "active", "rate", "flag") VALUES (1, 'Côte d''Ivoire', TRUE, 1.50, '\xcafe'::bytea);
+INSERT INTO "ref"."countries" ("code", "name", "active", "rate", "flag") VALUES (2, 'back\slash', FALSE, NULL, NULL);
+`,
+		},
+		{
+			dialect: "mysql",
+			expected: "INSERT INTO `ref`.`countries` (`code`, `name`, `active`, `rate`, `flag`) VALUES (1, 'Côte d''Ivoire', TRUE, 1.50, X'cafe');\n" +
+				"INSERT INTO `ref`.`countries` (`code`, `name`, `active`, `rate`, `flag`) VALUES (2, 'back\\\\slash', FALSE, NULL, NULL);\n",
+		},
+		{
+			dialect: "sqlserver",
+			expected: `INSERT INTO [ref].[countries] ([code], [name], [active], [rate], [flag]) VALUES (1, N'Côte d''Ivoire', 1, 1.50, 0xcafe);
+INSERT INTO [ref].[countries] ([code], [name], [active], [rate], [flag]) VALUES (2, N'back\slash', 0, NULL, NULL);
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			var buf bytes.Buffer
+			formatter := NewFormatter("sql-insert", &buf)
+			formatter.SetInsertOptions("ref.countries", tt.dialect, 1)
+			if err := formatter.FormatResult(insertResult()); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Unexpected output:\n%s\nexpected:\n%s", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatSQLInsertBatchAndHint(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("sql-insert", &buf)
+	formatter.SetInsertOptions("ignored", "sqlite3", 2)
+
+	result := &database.ExecutionResult{
+		Statement: "-- @insert-into fixtures\nSELECT id, created FROM t",
+		Columns:   []string{"id", "created"},
+		Rows: [][]interface{}{
+			{int64(1), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			{int64(2), nil},
+			{int64(3), nil},
+		},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := `INSERT INTO "fixtures" ("id", "created") VALUES
+  (1, '2024-01-02 03:04:05+00:00'),
+  (2, NULL);
+INSERT INTO "fixtures" ("id", "created") VALUES (3, NULL);
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFormatSQLInsertSQLServerTimes(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("sql-insert", &buf)
+	formatter.SetInsertOptions("events", "sqlserver", 1)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 123456700, time.FixedZone("", 2*60*60))
+	result := &database.ExecutionResult{
+		Statement: "SELECT * FROM events",
+		Columns:   []string{"logged", "precise", "zoned"},
+		ColumnTypes: []database.ColumnType{
+			{Name: "logged", DatabaseType: "DATETIME"},
+			{Name: "precise", DatabaseType: "DATETIME2"},
+			{Name: "zoned", DatabaseType: "DATETIMEOFFSET"},
+		},
+		Rows: [][]interface{}{{at, at, at}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// DATETIME only takes milliseconds
+	expected := "INSERT INTO [events] ([logged], [precise], [zoned]) VALUES " +
+		"(N'2024-01-02T03:04:05.123', N'2024-01-02T03:04:05.1234567', N'2024-01-02T03:04:05.1234567+02:00');\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestTimeLiteralLayout(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 123456700, time.FixedZone("", 2*60*60))
+	tests := []struct {
+		dialect      string
+		databaseType string
+		expected     string
+	}{
+		{"sqlite", "DATE", "2024-01-02"},
+		{"postgres", "date", "2024-01-02"},
+		{"sqlserver", "DATE", "2024-01-02"},
+		{"postgres", "TIME", "03:04:05.123456"},
+		{"mysql", "TIME", "03:04:05.123456"},
+		{"postgres", "TIMETZ", "03:04:05.123456+02:00"},
+		{"postgres", "TIMESTAMP", "2024-01-02 03:04:05.1234567"},
+		{"sqlite", "DATETIME", "2024-01-02 03:04:05.1234567"},
+		{"postgres", "TIMESTAMPTZ", "2024-01-02 03:04:05.1234567+02:00"},
+		{"sqlite", "", "2024-01-02 03:04:05.1234567+02:00"},
+		{"mysql", "TIMESTAMP", "2024-01-02 03:04:05.123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect+" "+tt.databaseType, func(t *testing.T) {
+			if result := at.Format(timeLiteralLayout(tt.dialect, tt.databaseType)); result != tt.expected {
+				t.Errorf("timeLiteralLayout(%q, %q) formats %q, expected %q", tt.dialect, tt.databaseType, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatSQLInsertMessages(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("sql-insert", &buf)
+
+	// Without a target table the rows cannot be rendered
+	err := formatter.FormatResult(insertResult())
+	if err == nil || !strings.Contains(err.Error(), "--insert-table") {
+		t.Errorf("Expected an error asking for a target table, got %v", err)
+	}
+
+	// Status messages become SQL comments so the output stays loadable
+	if err := formatter.FormatResult(&database.ExecutionResult{RowsAffected: 3}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "-- ") {
+		t.Errorf("Expected a SQL comment, got: %s", buf.String())
+	}
+}
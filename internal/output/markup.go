@@ -0,0 +1,248 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"gosqlpp/internal/database"
+)
+
+// SetHTMLDocument makes the html format write a complete HTML document with
+// the given stylesheet, which may be empty, instead of standalone tables.
+// The document is finished by Close.
+func (f *Formatter) SetHTMLDocument(enabled bool, css string) {
+	f.htmlDocument = enabled
+	f.htmlCSS = css
+}
+
+// Close finishes any output that spans several results, such as the end of
+// an HTML document or the whole document of the json-envelope format, and
+// closes the spool and output files
+func (f *Formatter) Close() error {
+	err := f.Unspool()
+	if finishErr := f.finish(); err == nil {
+		err = finishErr
+	}
+	if f.file != nil {
+		if closeErr := f.file.Close(); err == nil {
+			err = closeErr
+		}
+		f.file = nil
+	}
+	return err
+}
+
+// finish finishes the documents written to the current output
+func (f *Formatter) finish() error {
+	switch f.format {
+	case "json-envelope":
+		return f.writeEnvelope()
+	case "xlsx":
+		return f.writeWorkbook()
+	}
+	if !f.documentStarted {
+		return nil
+	}
+	f.documentStarted = false
+	_, err := fmt.Fprint(f.writer, "</body>\n</html>\n")
+	return err
+}
+
+// beginDocument writes the start of the HTML document before the first output
+func (f *Formatter) beginDocument() {
+	if f.format != "html" || !f.htmlDocument || f.documentStarted {
+		return
+	}
+	f.documentStarted = true
+
+	fmt.Fprint(f.writer, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>sqlpp results</title>\n")
+	if f.htmlCSS != "" {
+		fmt.Fprintf(f.writer, "<style>\n%s\n</style>\n", strings.TrimSpace(f.htmlCSS))
+	}
+	fmt.Fprint(f.writer, "</head>\n<body>\n")
+}
+
+// WriteMessage writes a status line in the current format
+func (f *Formatter) WriteMessage(message string) error {
+	return f.writeMessage(f.format, message)
+}
+
+// WriteSQL writes a SQL script as is, as a code block in markup formats and
+// to the message writer for ndjson, json-envelope and xlsx
+func (f *Formatter) WriteSQL(script string) error {
+	var err error
+	switch f.format {
+	case "markdown", "html", "asciidoc":
+		err = f.writeMarkupBlock(script)
+	case "ndjson", "json-envelope", "xlsx":
+		_, err = fmt.Fprint(f.messages, script)
+	default:
+		_, err = fmt.Fprint(f.writer, script)
+	}
+	return err
+}
+
+// writeMessage writes a status line such as a row count or an error, as a
+// paragraph in HTML, as a comment in SQL, to the message writer for ndjson,
+// json-envelope and xlsx, and as plain text in other formats
+func (f *Formatter) writeMessage(format, message string) error {
+	var err error
+	switch format {
+	case "html":
+		f.beginDocument()
+		_, err = fmt.Fprintf(f.writer, "<p>%s</p>\n", html.EscapeString(message))
+	case "sql-insert":
+		_, err = fmt.Fprintf(f.writer, "-- %s\n", strings.ReplaceAll(message, "\n", "\n-- "))
+	case "ndjson", "json-envelope", "xlsx":
+		_, err = fmt.Fprintf(f.messages, "%s\n", message)
+	default:
+		_, err = fmt.Fprintf(f.writer, "%s\n", message)
+	}
+	return err
+}
+
+// formatMarkup writes result sets in a markup format, titling each set when
+// there are several
+func (f *Formatter) formatMarkup(format string, sets []database.ResultSet) error {
+	writeTable := f.markupTableWriter(format)
+	for i, set := range sets {
+		if len(sets) > 1 {
+			if i > 0 {
+				fmt.Fprintln(f.writer)
+			}
+			f.writeMessage(format, fmt.Sprintf("Result set %d:", i+1))
+			if format != "html" {
+				fmt.Fprintln(f.writer)
+			}
+		}
+
+		writeTable(set.Columns, set.Rows)
+		if format != "html" {
+			fmt.Fprintln(f.writer)
+		}
+		f.writeMessage(format, fmt.Sprintf("(%d rows)", set.RowCount))
+	}
+	return nil
+}
+
+// markupTableWriter returns the table writer of a markup format
+func (f *Formatter) markupTableWriter(format string) func(columns []string, rows [][]interface{}) {
+	switch format {
+	case "html":
+		return f.writeHTMLTable
+	case "asciidoc":
+		return f.writeAsciiDocTable
+	default:
+		return f.writeMarkdownTable
+	}
+}
+
+// writeMarkdownTable writes a GitHub-flavored Markdown pipe table
+func (f *Formatter) writeMarkdownTable(columns []string, rows [][]interface{}) {
+	cells := make([]string, len(columns))
+	separators := make([]string, len(columns))
+	for i, col := range columns {
+		cells[i] = escapeMarkdown(col)
+		separators[i] = "---"
+	}
+	fmt.Fprintf(f.writer, "| %s |\n", strings.Join(cells, " | "))
+	fmt.Fprintf(f.writer, "| %s |\n", strings.Join(separators, " | "))
+
+	for _, row := range rows {
+		for i := range columns {
+			cells[i] = escapeMarkdown(f.formatValue(rowValue(row, i)))
+		}
+		fmt.Fprintf(f.writer, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+// escapeMarkdown escapes text for a Markdown table cell
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// writeHTMLTable writes an HTML table
+func (f *Formatter) writeHTMLTable(columns []string, rows [][]interface{}) {
+	f.beginDocument()
+
+	fmt.Fprint(f.writer, "<table>\n  <thead>\n    <tr>")
+	for _, col := range columns {
+		fmt.Fprintf(f.writer, "<th>%s</th>", html.EscapeString(col))
+	}
+	fmt.Fprint(f.writer, "</tr>\n  </thead>\n  <tbody>\n")
+
+	for _, row := range rows {
+		fmt.Fprint(f.writer, "    <tr>")
+		for i := range columns {
+			val := rowValue(row, i)
+			if val == nil {
+				fmt.Fprintf(f.writer, `<td class="null">%s</td>`, html.EscapeString(f.formatValue(nil)))
+				continue
+			}
+			fmt.Fprintf(f.writer, "<td>%s</td>", html.EscapeString(f.formatValue(val)))
+		}
+		fmt.Fprint(f.writer, "</tr>\n")
+	}
+	fmt.Fprint(f.writer, "  </tbody>\n</table>\n")
+}
+
+// writeAsciiDocTable writes an AsciiDoc table with a header row
+func (f *Formatter) writeAsciiDocTable(columns []string, rows [][]interface{}) {
+	fmt.Fprintf(f.writer, "[options=\"header\"]\n|===\n")
+
+	cells := make([]string, len(columns))
+	for i, col := range columns {
+		cells[i] = "|" + escapeAsciiDoc(col)
+	}
+	fmt.Fprintln(f.writer, strings.Join(cells, " "))
+
+	for _, row := range rows {
+		for i := range columns {
+			cells[i] = "|" + escapeAsciiDoc(f.formatValue(rowValue(row, i)))
+		}
+		fmt.Fprintln(f.writer, strings.Join(cells, " "))
+	}
+	fmt.Fprintln(f.writer, "|===")
+}
+
+// escapeAsciiDoc escapes text for an AsciiDoc table cell, keeping line
+// breaks as hard breaks
+func escapeAsciiDoc(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", " +\n")
+}
+
+// rowValue returns the value of column i, or nil for short rows
+func rowValue(row []interface{}, i int) interface{} {
+	if i < len(row) {
+		return row[i]
+	}
+	return nil
+}
+
+// writeMarkupBlock writes preformatted text, such as a query plan, as a code
+// block in the current markup format
+func (f *Formatter) writeMarkupBlock(text string) error {
+	var err error
+	switch f.format {
+	case "html":
+		f.beginDocument()
+		_, err = fmt.Fprintf(f.writer, "<pre>%s</pre>\n", html.EscapeString(strings.TrimRight(text, "\n")))
+	case "markdown":
+		_, err = fmt.Fprintf(f.writer, "```\n%s```\n", text)
+	default:
+		_, err = fmt.Fprintf(f.writer, "----\n%s----\n", text)
+	}
+	return err
+}
+
+// formatDataAsMarkup formats generic data as a table in a markup format
+func (f *Formatter) formatDataAsMarkup(data []Record) error {
+	f.markupTableWriter(f.format)(dataRows(data))
+	return nil
+}
@@ -0,0 +1,160 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/database"
+)
+
+func markupResult() *database.ExecutionResult {
+	return &database.ExecutionResult{
+		Columns: []string{"id", "note"},
+		Rows: [][]interface{}{
+			{int64(1), "a|b"},
+			{int64(2), nil},
+			{int64(3), "<b>line one\nline two</b>"},
+		},
+	}
+}
+
+func TestFormatMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewFormatter("markdown", &buf).FormatResult(markupResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := `| id | note |
+| --- | --- |
+| 1 | a\|b |
+| 2 | NULL |
+| 3 | <b>line one<br>line two</b> |
+
+(3 rows)
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected markdown output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFormatHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewFormatter("html", &buf).FormatResult(markupResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	if !strings.HasPrefix(output, "<table>") {
+		t.Errorf("Expected a standalone table, got: %s", output)
+	}
+	for _, expected := range []string{
+		"<th>id</th><th>note</th>",
+		`<td>2</td><td class="null">NULL</td>`,
+		"<td>&lt;b&gt;line one\nline two&lt;/b&gt;</td>",
+		"<p>(3 rows)</p>",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got: %s", expected, output)
+		}
+	}
+}
+
+func TestFormatHTMLNullDisplay(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("html", &buf)
+	none := "<none>"
+	formatter.SetValueOptions(ValueOptions{NullDisplay: &none})
+	if err := formatter.FormatResult(markupResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if expected := `<td>2</td><td class="null">&lt;none&gt;</td>`; !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected output to contain %q, got: %s", expected, buf.String())
+	}
+}
+
+func TestFormatHTMLDocument(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("html", &buf)
+	formatter.SetHTMLDocument(true, "td { padding: 4px; }")
+
+	for i := 0; i < 2; i++ {
+		if err := formatter.FormatResult(markupResult()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := formatter.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	if !strings.HasPrefix(output, "<!DOCTYPE html>") || !strings.HasSuffix(output, "</body>\n</html>\n") {
+		t.Errorf("Expected a complete HTML document, got: %s", output)
+	}
+	if strings.Count(output, "<!DOCTYPE html>") != 1 || strings.Count(output, "<table>") != 2 {
+		t.Errorf("Expected one document holding both tables, got: %s", output)
+	}
+	if !strings.Contains(output, "<style>\ntd { padding: 4px; }\n</style>") {
+		t.Errorf("Expected the stylesheet to be embedded, got: %s", output)
+	}
+}
+
+func TestFormatAsciiDoc(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewFormatter("asciidoc", &buf).FormatResult(markupResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := `[options="header"]
+|===
+|id |note
+|1 |a\|b
+|2 |NULL
+|3 |<b>line one +
+line two</b>
+|===
+
+(3 rows)
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected asciidoc output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFormatDataMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	data := []Record{
+		{{Key: "name", Value: "users"}, {Key: "type", Value: "table"}},
+	}
+	if err := NewFormatter("markdown", &buf).FormatData(data); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "| name | type |\n| --- | --- |\n| users | table |\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected markdown output:\n%s", buf.String())
+	}
+}
+
+func TestWriteSQL(t *testing.T) {
+	script := "CREATE TABLE t (id INTEGER)\ngo\n"
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"table", script},
+		{"json", script},
+		{"markdown", "```\n" + script + "```\n"},
+		{"html", "<pre>CREATE TABLE t (id INTEGER)\ngo</pre>\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := NewFormatter(tt.format, &buf).WriteSQL(script); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.HasSuffix(buf.String(), tt.expected) {
+			t.Errorf("%s: expected output ending in %q, got %q", tt.format, tt.expected, buf.String())
+		}
+	}
+}
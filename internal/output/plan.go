@@ -0,0 +1,133 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gosqlpp/internal/database"
+
+	"gopkg.in/yaml.v3"
+)
+
+// planDocument is the structured form of an explained statement
+type planDocument struct {
+	File      string               `json:"file" yaml:"file"`
+	Line      int                  `json:"line" yaml:"line"`
+	Statement string               `json:"statement" yaml:"statement"`
+	Driver    string               `json:"driver" yaml:"driver"`
+	Plan      []*database.PlanNode `json:"plan" yaml:"plan"`
+}
+
+// formatPlan formats the query plan of an explained statement
+func (f *Formatter) formatPlan(result *database.ExecutionResult) error {
+	document := planDocument{
+		File:      result.FileName,
+		Line:      result.LineNumber,
+		Statement: result.Statement,
+		Driver:    result.Plan.Driver,
+		Plan:      result.Plan.Nodes,
+	}
+
+	switch f.format {
+	case "table", "vertical", "auto", "template":
+		// Templates render rows, so a plan is written as the plain tree
+		_, err := fmt.Fprint(f.writer, planTree(result))
+		return err
+	case "json":
+		encoder := json.NewEncoder(f.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+	case "ndjson":
+		// One compact line, like every other ndjson record
+		return json.NewEncoder(f.writer).Encode(document)
+	case "json-envelope":
+		f.addEnvelopeResult(result)
+		return nil
+	case "yaml":
+		encoder := yaml.NewEncoder(f.writer)
+		defer encoder.Close()
+		return encoder.Encode(document)
+	case "csv", "tsv":
+		return f.formatPlanAsCSV(document.Plan)
+	case "markdown", "html", "asciidoc":
+		return f.writeMarkupBlock(planTree(result))
+	case "sql-insert", "xlsx":
+		return f.writeMessage(f.format, strings.TrimRight(planTree(result), "\n"))
+	default:
+		return fmt.Errorf("unsupported output format: %s", f.format)
+	}
+}
+
+// planTree renders the plan as an indented tree
+func planTree(result *database.ExecutionResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Query plan for %s:%d (%s)\n", result.FileName, result.LineNumber, result.Plan.Driver)
+	writePlanTree(&b, result.Plan.Nodes, "")
+	return b.String()
+}
+
+// writePlanTree writes plan nodes and their children with tree branches
+func writePlanTree(b *strings.Builder, nodes []*database.PlanNode, prefix string) {
+	for i, node := range nodes {
+		branch, childPrefix := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, childPrefix = "└── ", "    "
+		}
+
+		b.WriteString(prefix + branch + node.Operation)
+		if properties := planProperties(node); properties != "" {
+			b.WriteString(" (" + properties + ")")
+		}
+		b.WriteString("\n")
+
+		writePlanTree(b, node.Children, prefix+childPrefix)
+	}
+}
+
+// planProperties formats node properties as sorted key=value pairs
+func planProperties(node *database.PlanNode) string {
+	keys := make([]string, 0, len(node.Properties))
+	for key := range node.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + node.Properties[key]
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// formatPlanAsCSV writes one row per plan node, linked to its parent by id
+func (f *Formatter) formatPlanAsCSV(nodes []*database.PlanNode) error {
+	writer, err := f.newDelimitedWriter(f.format)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.WriteHeader([]string{"id", "parent_id", "depth", "operation", "properties"}); err != nil {
+		return err
+	}
+
+	nextID := 0
+	var write func(nodes []*database.PlanNode, parentID string, depth int) error
+	write = func(nodes []*database.PlanNode, parentID string, depth int) error {
+		for _, node := range nodes {
+			nextID++
+			id := strconv.Itoa(nextID)
+			row := []string{id, parentID, strconv.Itoa(depth), node.Operation, planProperties(node)}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+			if err := write(node.Children, id, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return write(nodes, "", 0)
+}
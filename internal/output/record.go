@@ -0,0 +1,113 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Field is a named value of a Record
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Record is a row of generic data whose fields keep their order, so that
+// tables, CSV headers and JSON or YAML objects list them as they were added
+type Record []Field
+
+// Get returns the value of the field named key
+func (r Record) Get(key string) (interface{}, bool) {
+	for _, field := range r {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the value of the field named key, adding the field at the end
+// when the record does not have it yet
+func (r *Record) Set(key string, value interface{}) {
+	for i := range *r {
+		if (*r)[i].Key == key {
+			(*r)[i].Value = value
+			return
+		}
+	}
+	*r = append(*r, Field{Key: key, Value: value})
+}
+
+// Keys returns the field names in order
+func (r Record) Keys() []string {
+	keys := make([]string, len(r))
+	for i, field := range r {
+		keys[i] = field.Key
+	}
+	return keys
+}
+
+// MarshalJSON writes the record as a JSON object with keys in field order
+func (r Record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML writes the record as a YAML mapping with keys in field order
+func (r Record) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, field := range r {
+		var value yaml.Node
+		if err := value.Encode(field.Value); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field.Key},
+			&value,
+		)
+	}
+	return node, nil
+}
+
+// dataRows converts records to columns and rows. Columns follow the field
+// order of the first record, followed by fields that only later records have.
+func dataRows(data []Record) ([]string, [][]interface{}) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, record := range data {
+		for _, field := range record {
+			if !seen[field.Key] {
+				seen[field.Key] = true
+				columns = append(columns, field.Key)
+			}
+		}
+	}
+
+	rows := make([][]interface{}, len(data))
+	for i, record := range data {
+		rows[i] = make([]interface{}, len(columns))
+		for j, col := range columns {
+			rows[i][j], _ = record.Get(col)
+		}
+	}
+	return columns, rows
+}
@@ -0,0 +1,79 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"gosqlpp/internal/database"
+)
+
+func TestRecordSet(t *testing.T) {
+	record := Record{{Key: "b", Value: 1}}
+	record.Set("a", 2)
+	record.Set("b", 3)
+
+	if keys := record.Keys(); len(keys) != 2 || keys[0] != "b" || keys[1] != "a" {
+		t.Errorf("Expected keys [b a], got %v", keys)
+	}
+	if value, ok := record.Get("b"); !ok || value != 3 {
+		t.Errorf("Expected b to be replaced in place, got %v", value)
+	}
+	if _, ok := record.Get("c"); ok {
+		t.Error("Expected c to be missing")
+	}
+}
+
+func TestFormatDataKeepsFieldOrder(t *testing.T) {
+	data := []Record{
+		{{Key: "name", Value: "main"}, {Key: "driver", Value: "sqlite3"}, {Key: "is_default", Value: true}},
+		{{Key: "name", Value: "reporting"}, {Key: "driver", Value: "postgres"}, {Key: "is_default", Value: false}, {Key: "notes", Value: "replica"}},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"csv", "name,driver,is_default,notes\nmain,sqlite3,true,\nreporting,postgres,false,replica\n"},
+		{"table", "name       driver    is_default  notes    \nmain       sqlite3   true        NULL     \nreporting  postgres  false       replica  \n"},
+		{"json", "[\n  {\n    \"name\": \"main\",\n    \"driver\": \"sqlite3\",\n    \"is_default\": true\n  },\n" +
+			"  {\n    \"name\": \"reporting\",\n    \"driver\": \"postgres\",\n    \"is_default\": false,\n    \"notes\": \"replica\"\n  }\n]\n"},
+		{"yaml", "- name: main\n  driver: sqlite3\n  is_default: true\n- name: reporting\n  driver: postgres\n  is_default: false\n  notes: replica\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			// Repeat to catch any dependence on map iteration order
+			for i := 0; i < 5; i++ {
+				var buf bytes.Buffer
+				if err := NewFormatter(tt.format, &buf).FormatData(data); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if buf.String() != tt.expected {
+					t.Fatalf("Unexpected %s output:\n%q\nexpected:\n%q", tt.format, buf.String(), tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatJSONKeepsColumnOrder(t *testing.T) {
+	result := &database.ExecutionResult{
+		Columns: []string{"zeta", "alpha", "mid"},
+		Rows:    [][]interface{}{{int64(1), "a", nil}},
+	}
+
+	for _, format := range []string{"json", "yaml", "ndjson"} {
+		var buf bytes.Buffer
+		if err := NewFormatter(format, &buf).FormatResult(result); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := map[string]string{
+			"json":   "[\n  {\n    \"zeta\": 1,\n    \"alpha\": \"a\",\n    \"mid\": null\n  }\n]\n",
+			"yaml":   "- zeta: 1\n  alpha: a\n  mid: null\n",
+			"ndjson": "{\"zeta\":1,\"alpha\":\"a\",\"mid\":null}\n",
+		}[format]
+		if buf.String() != expected {
+			t.Errorf("Unexpected %s output:\n%q\nexpected:\n%q", format, buf.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gosqlpp/internal/database"
+)
+
+// DefaultOutputName is the file name pattern used by SetOutputDir when none
+// is given
+const DefaultOutputName = "{file}_{line}.{ext}"
+
+// formatExtensions maps output formats to the extension of files holding them
+var formatExtensions = map[string]string{
+	"table":         "txt",
+	"vertical":      "txt",
+	"auto":          "txt",
+	"json":          "json",
+	"yaml":          "yaml",
+	"csv":           "csv",
+	"tsv":           "tsv",
+	"markdown":      "md",
+	"html":          "html",
+	"asciidoc":      "adoc",
+	"sql-insert":    "sql",
+	"ndjson":        "ndjson",
+	"json-envelope": "json",
+	"template":      "txt",
+	"xlsx":          "xlsx",
+}
+
+// extensionFormats maps file extensions to the output format they imply
+var extensionFormats = map[string]string{
+	".json":   "json",
+	".yaml":   "yaml",
+	".yml":    "yaml",
+	".csv":    "csv",
+	".tsv":    "tsv",
+	".md":     "markdown",
+	".html":   "html",
+	".htm":    "html",
+	".adoc":   "asciidoc",
+	".sql":    "sql-insert",
+	".ndjson": "ndjson",
+	".jsonl":  "ndjson",
+	".xlsx":   "xlsx",
+}
+
+// FormatExtension returns the file extension, without the dot, used for
+// files in the given output format
+func FormatExtension(format string) string {
+	if ext, ok := formatExtensions[format]; ok {
+		return ext
+	}
+	return "txt"
+}
+
+// FormatForFile returns the output format implied by a file's extension, or
+// an empty string when the extension does not name one
+func FormatForFile(path string) string {
+	return extensionFormats[strings.ToLower(filepath.Ext(path))]
+}
+
+// savedOutput is the output replaced by a redirection, with the state of the
+// documents written to it, restored when the redirection ends
+type savedOutput struct {
+	writer           io.Writer
+	format           string
+	documentStarted  bool
+	envelope         []envelopeStatement
+	sheets           []xlsxSheet
+	sheetStatements  int
+	delimitedStarted bool
+}
+
+// redirect sends later output to w in the given format and returns the
+// output it replaces
+func (f *Formatter) redirect(w io.Writer, format string) *savedOutput {
+	saved := &savedOutput{
+		writer:           f.writer,
+		format:           f.format,
+		documentStarted:  f.documentStarted,
+		envelope:         f.envelope,
+		sheets:           f.sheets,
+		sheetStatements:  f.sheetStatements,
+		delimitedStarted: f.delimitedStarted,
+	}
+	f.writer = w
+	f.format = format
+	f.documentStarted = false
+	f.envelope = nil
+	f.sheets = nil
+	f.sheetStatements = 0
+	f.delimitedStarted = false
+	return saved
+}
+
+// restore finishes the documents of the redirected output and returns to
+// the saved output
+func (f *Formatter) restore(saved *savedOutput) error {
+	err := f.finish()
+	f.writer = saved.writer
+	f.format = saved.format
+	f.documentStarted = saved.documentStarted
+	f.envelope = saved.envelope
+	f.sheets = saved.sheets
+	f.sheetStatements = saved.sheetStatements
+	f.delimitedStarted = saved.delimitedStarted
+	return err
+}
+
+// SetOutputFile creates the file at path and writes all output to it. The
+// file is closed by Close.
+func (f *Formatter) SetOutputFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	f.file = file
+	f.writer = file
+	return nil
+}
+
+// Spool sends later output to the file at path until Unspool, in the format
+// named by the file's extension or in the current format otherwise
+func (f *Formatter) Spool(path string) error {
+	if err := f.Unspool(); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create spool file: %w", err)
+	}
+	format := FormatForFile(path)
+	if format == "" {
+		format = f.format
+	}
+	f.spool = file
+	f.spooled = f.redirect(file, format)
+	return nil
+}
+
+// Unspool closes the spool file and sends later output where it went before
+// Spool was called
+func (f *Formatter) Unspool() error {
+	if f.spool == nil {
+		return nil
+	}
+	err := f.restore(f.spooled)
+	if closeErr := f.spool.Close(); err == nil {
+		err = closeErr
+	}
+	f.spool = nil
+	f.spooled = nil
+	return err
+}
+
+// SetOutputDir writes the rows of each statement to a file of its own in
+// dir. The file name is built from pattern, where {file} is replaced by the
+// SQL file name without extension, {line} by the statement's line, {n} by a
+// counter of files written and {ext} by the extension of the output format.
+// Messages such as errors and row counts still go to the regular output.
+func (f *Formatter) SetOutputDir(dir, pattern string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if pattern == "" {
+		pattern = DefaultOutputName
+	}
+	f.outputDir = dir
+	f.outputName = pattern
+	f.outputFiles = make(map[string]bool)
+	return nil
+}
+
+// writesOwnFile reports whether a result goes to a file of its own in the
+// output directory: only statements that return rows or a plan do, unless
+// output is being spooled
+func (f *Formatter) writesOwnFile(result *database.ExecutionResult) bool {
+	if f.outputDir == "" || f.spool != nil || result.FileName == "" {
+		return false
+	}
+	if result.Error != nil || result.DryRun {
+		return false
+	}
+	return result.Plan != nil || len(result.GetResultSets()) > 0
+}
+
+// formatResultToFile writes a result to its own file in the output directory.
+// A name that expands to a file written earlier in the run, such as {file}
+// for a/report.sql and b/report.sql, is an error rather than an overwrite.
+func (f *Formatter) formatResultToFile(result *database.ExecutionResult) error {
+	f.outputCount++
+	path := filepath.Join(f.outputDir, f.outputFileName(result))
+	if f.outputFiles[path] {
+		return fmt.Errorf("output file %s was already written for an earlier statement; add {n} to --output-name to keep both", path)
+	}
+	f.outputFiles[path] = true
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	saved := f.redirect(file, f.format)
+	err = f.formatResult(result)
+	if restoreErr := f.restore(saved); err == nil {
+		err = restoreErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// outputFileName expands the output name pattern for a result
+func (f *Formatter) outputFileName(result *database.ExecutionResult) string {
+	name := filepath.Base(result.FileName)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.Trim(name, "<>")
+
+	return strings.NewReplacer(
+		"{file}", name,
+		"{line}", strconv.Itoa(result.LineNumber),
+		"{n}", strconv.Itoa(f.outputCount),
+		"{ext}", FormatExtension(f.resultFormat(result)),
+	).Replace(f.outputName)
+}
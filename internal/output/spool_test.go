@@ -0,0 +1,145 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/database"
+)
+
+func TestFormatForFile(t *testing.T) {
+	tests := map[string]string{
+		"report.csv":   "csv",
+		"REPORT.JSON":  "json",
+		"data.yml":     "yaml",
+		"notes.md":     "markdown",
+		"events.jsonl": "ndjson",
+		"output.txt":   "",
+		"noextension":  "",
+	}
+	for path, expected := range tests {
+		if got := FormatForFile(path); got != expected {
+			t.Errorf("FormatForFile(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestSpool(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("html", &buf)
+	formatter.SetHTMLDocument(true, "")
+	result := &database.ExecutionResult{
+		Columns: []string{"id"},
+		Rows:    [][]interface{}{{int64(1)}},
+	}
+
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spoolPath := filepath.Join(t.TempDir(), "spooled.json")
+	if err := formatter.Spool(spoolPath); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := formatter.Unspool(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := formatter.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spooled, err := os.ReadFile(spoolPath)
+	if err != nil {
+		t.Fatalf("Failed to read spool file: %v", err)
+	}
+	if string(spooled) != "[\n  {\n    \"id\": 1\n  }\n]\n" {
+		t.Errorf("Expected the spooled result in the format of the file extension, got: %q", spooled)
+	}
+
+	// The HTML document continues around the spooled output
+	output := buf.String()
+	if strings.Count(output, "<!DOCTYPE html>") != 1 || strings.Count(output, "<table>") != 2 || !strings.HasSuffix(output, "</html>\n") {
+		t.Errorf("Expected a single HTML document with two tables, got:\n%s", output)
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "results")
+	var buf bytes.Buffer
+	formatter := NewFormatter("csv", &buf)
+	if err := formatter.SetOutputDir(dir, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	results := []*database.ExecutionResult{
+		{FileName: "reports/daily.sql", LineNumber: 3, Columns: []string{"n"}, Rows: [][]interface{}{{int64(1)}}},
+		{FileName: "reports/daily.sql", LineNumber: 7, RowsAffected: 2},
+		{FileName: "reports/daily.sql", LineNumber: 9, Statement: "-- @output json\nSELECT 2 AS n",
+			Columns: []string{"n"}, Rows: [][]interface{}{{int64(2)}}},
+	}
+	for _, result := range results {
+		if err := formatter.FormatResult(result); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		"daily_3.csv":  "n\n1\n",
+		"daily_9.json": "[\n  {\n    \"n\": 2\n  }\n]\n",
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != len(expected) {
+		t.Errorf("Expected %d files, got %d", len(expected), len(entries))
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected %s to be written: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s: %q", name, data)
+		}
+	}
+
+	// Statements without rows still report on the regular output
+	if buf.String() != "(2 rows affected)\n" {
+		t.Errorf("Expected the row count on the regular output, got: %q", buf.String())
+	}
+}
+
+func TestOutputDirRefusesOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	formatter := NewFormatter("csv", &buf)
+	if err := formatter.SetOutputDir(dir, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	first := &database.ExecutionResult{FileName: "a/report.sql", LineNumber: 1, Columns: []string{"n"}, Rows: [][]interface{}{{int64(1)}}}
+	second := &database.ExecutionResult{FileName: "b/report.sql", LineNumber: 1, Columns: []string{"n"}, Rows: [][]interface{}{{int64(2)}}}
+	if err := formatter.FormatResult(first); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	err := formatter.FormatResult(second)
+	if err == nil || !strings.Contains(err.Error(), "{n}") {
+		t.Errorf("Expected an error about the file name collision, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "report_1.csv"))
+	if err != nil || string(data) != "n\n1\n" {
+		t.Errorf("Expected the first file to be kept, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,249 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"gosqlpp/internal/database"
+
+	"github.com/rodaine/table"
+)
+
+// truncationMarker ends values cut short to fit their column
+const truncationMarker = "…"
+
+// minFitWidth is the narrowest a column is made when a table is shrunk to
+// fit the terminal
+const minFitWidth = 10
+
+// ANSI escape sequences used when color is enabled
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+)
+
+// SetMaxColumnWidth limits table columns to width characters, wrapping
+// longer values onto further lines when wrap is set and truncating them
+// otherwise. A width of 0 leaves columns as wide as their values, unless the
+// table has to be shrunk to fit the terminal.
+func (f *Formatter) SetMaxColumnWidth(width int, wrap bool) {
+	f.maxColWidth = width
+	f.wrap = wrap
+}
+
+// SetColor sets when table output and errors are colored: always, never,
+// or auto for when the output is a terminal and NO_COLOR is not set
+func (f *Formatter) SetColor(mode string) {
+	f.color = mode
+}
+
+// useColor reports whether output in the given format is colored. Only the
+// formats meant for reading in a terminal are.
+func (f *Formatter) useColor(format string) bool {
+	switch format {
+	case "table", "vertical", "auto":
+	default:
+		return false
+	}
+	switch f.color {
+	case "always":
+		return true
+	case "auto":
+		_, ok := terminalFile(f.writer)
+		return ok && os.Getenv("NO_COLOR") == ""
+	}
+	return false
+}
+
+// writeTable writes rows as a table with aligned columns. Numbers are
+// right-aligned, and values wider than their column are wrapped or
+// truncated. With color, the header is bold and NULL values are dimmed.
+func (f *Formatter) writeTable(columns []string, rows [][]interface{}) {
+	texts := make([][]string, len(rows))
+	for i, row := range rows {
+		texts[i] = make([]string, len(columns))
+		for j := range columns {
+			texts[i][j] = f.formatValue(rowValue(row, j))
+		}
+	}
+	limits := f.columnLimits(columns, texts)
+	color := f.useColor("table")
+
+	// Fit every cell, then measure the columns they make
+	headers := make([]interface{}, len(columns))
+	widths := make([]int, len(columns))
+	for j, col := range columns {
+		col = truncateLine(col, limits[j])
+		headers[j] = col
+		widths[j] = utf8.RuneCountInString(col)
+	}
+	cells := make([][][]string, len(rows))
+	for i := range rows {
+		cells[i] = make([][]string, len(columns))
+		for j := range columns {
+			cells[i][j] = f.fitLines(texts[i][j], limits[j])
+			for _, line := range cells[i][j] {
+				widths[j] = max(widths[j], utf8.RuneCountInString(line))
+			}
+		}
+	}
+
+	tbl := table.New(headers...)
+	tbl.WithWriter(f.writer)
+	tbl.WithWidthFunc(visibleWidth)
+	if color {
+		tbl.WithHeaderFormatter(func(format string, vals ...interface{}) string {
+			return ansiBold + strings.TrimSuffix(fmt.Sprintf(format, vals...), "\n") + ansiReset + "\n"
+		})
+	}
+
+	for i, row := range rows {
+		values := make([]interface{}, len(columns))
+		for j := range columns {
+			val := rowValue(row, j)
+			lines := cells[i][j]
+			for k, line := range lines {
+				if isNumeric(val) {
+					line = strings.Repeat(" ", widths[j]-utf8.RuneCountInString(line)) + line
+				}
+				if color && val == nil {
+					line = ansiDim + line + ansiReset
+				}
+				lines[k] = line
+			}
+			values[j] = strings.Join(lines, "\n")
+		}
+		tbl.AddRow(values...)
+	}
+
+	tbl.Print()
+}
+
+// columnLimits returns the widest each column may be, or 0 for no limit:
+// the maximum column width, narrowed further when the table would not fit
+// the terminal
+func (f *Formatter) columnLimits(columns []string, texts [][]string) []int {
+	widths := make([]int, len(columns))
+	for j, col := range columns {
+		widths[j] = utf8.RuneCountInString(col)
+	}
+	for _, row := range texts {
+		for j, text := range row {
+			for _, line := range strings.Split(text, "\n") {
+				widths[j] = max(widths[j], utf8.RuneCountInString(line))
+			}
+		}
+	}
+
+	limits := make([]int, len(columns))
+	if f.maxColWidth > 0 {
+		for j := range widths {
+			limits[j] = f.maxColWidth
+			widths[j] = min(widths[j], f.maxColWidth)
+		}
+	}
+
+	available := f.outputWidth()
+	if available <= 0 || tableTotal(widths, 0) <= available {
+		return limits
+	}
+
+	// Shrink the widest columns until the table fits, but not below
+	// minFitWidth, and leave it wider than the terminal if it still
+	// does not fit
+	limit := 0
+	for _, w := range widths {
+		limit = max(limit, w)
+	}
+	for limit > minFitWidth && tableTotal(widths, limit) > available {
+		limit--
+	}
+	for j := range limits {
+		if limits[j] == 0 || limits[j] > limit {
+			limits[j] = limit
+		}
+	}
+	return limits
+}
+
+// tableTotal returns the width of a table with the given column widths,
+// each capped at limit unless limit is 0
+func tableTotal(widths []int, limit int) int {
+	total := 0
+	for _, w := range widths {
+		if limit > 0 {
+			w = min(w, limit)
+		}
+		total += w + tablePadding
+	}
+	return total
+}
+
+// fitLines splits a value into its lines, wrapping or truncating those
+// longer than limit; a limit of 0 leaves them unchanged
+func (f *Formatter) fitLines(text string, limit int) []string {
+	lines := strings.Split(text, "\n")
+	if limit <= 0 {
+		return lines
+	}
+
+	var fitted []string
+	for _, line := range lines {
+		runes := []rune(line)
+		switch {
+		case len(runes) <= limit:
+			fitted = append(fitted, line)
+		case f.wrap:
+			for len(runes) > limit {
+				fitted = append(fitted, string(runes[:limit]))
+				runes = runes[limit:]
+			}
+			fitted = append(fitted, string(runes))
+		default:
+			fitted = append(fitted, truncateLine(line, limit))
+		}
+	}
+	return fitted
+}
+
+// truncateLine shortens a line to limit characters, ending it with the
+// truncation marker; a limit of 0 leaves it unchanged
+func truncateLine(line string, limit int) string {
+	runes := []rune(line)
+	if limit <= 0 || len(runes) <= limit {
+		return line
+	}
+	return string(runes[:limit-1]) + truncationMarker
+}
+
+// isNumeric reports whether a value is a number, which tables right-align
+func isNumeric(val interface{}) bool {
+	switch val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, database.Decimal:
+		return true
+	}
+	return false
+}
+
+// visibleWidth returns the width of s in a terminal, not counting ANSI
+// escape sequences
+func visibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size - 1
+		width++
+	}
+	return width
+}
@@ -0,0 +1,111 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/database"
+)
+
+func tableTestResult() *database.ExecutionResult {
+	return &database.ExecutionResult{
+		Columns: []string{"id", "note"},
+		Rows: [][]interface{}{
+			{int64(1), "a long note that does not fit"},
+			{int64(100), nil},
+		},
+	}
+}
+
+func TestTableRightAlignsNumbers(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+	if err := formatter.FormatResult(tableTestResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if !strings.HasPrefix(lines[1], "  1  ") || !strings.HasPrefix(lines[2], "100  ") {
+		t.Errorf("Expected right-aligned ids, got:\n%s", buf.String())
+	}
+}
+
+func TestTableMaxColumnWidth(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+	formatter.SetMaxColumnWidth(10, false)
+	if err := formatter.FormatResult(tableTestResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "a long no…") || strings.Contains(buf.String(), "does not fit") {
+		t.Errorf("Expected the note to be truncated, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	formatter.SetMaxColumnWidth(10, true)
+	if err := formatter.FormatResult(tableTestResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	expected := []string{"  1  a long not  ", "     e that doe  ", "     s not fit   "}
+	for i, line := range expected {
+		if lines[i+1] != line {
+			t.Errorf("Line %d: expected %q, got %q", i+1, line, lines[i+1])
+		}
+	}
+}
+
+func TestTableFitsWidth(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+	formatter.SetWidth(20)
+	if err := formatter.FormatResult(tableTestResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if n := len([]rune(line)); n > 20 {
+			t.Errorf("Expected lines of at most 20 characters, got %d in %q", n, line)
+		}
+	}
+}
+
+func TestTableColor(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+	formatter.SetColor("always")
+	if err := formatter.FormatResult(tableTestResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	output := buf.String()
+	if !strings.HasPrefix(output, ansiBold+"id") {
+		t.Errorf("Expected a bold header, got %q", output)
+	}
+	if !strings.Contains(output, ansiDim+"NULL"+ansiReset) {
+		t.Errorf("Expected a dimmed NULL, got %q", output)
+	}
+
+	buf.Reset()
+	if err := formatter.FormatResult(&database.ExecutionResult{Error: errors.New("boom")}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), ansiRed) {
+		t.Errorf("Expected a red error, got %q", buf.String())
+	}
+
+	// Color is only used when the output is a terminal in auto mode
+	buf.Reset()
+	formatter.SetColor("auto")
+	if err := formatter.FormatResult(tableTestResult()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("Expected no color for a buffer, got %q", buf.String())
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	if got := visibleWidth(ansiBold + "héllo" + ansiReset); got != 5 {
+		t.Errorf("visibleWidth = %d, expected 5", got)
+	}
+}
@@ -0,0 +1,286 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gosqlpp/internal/database"
+)
+
+// templateResult is the data a template is executed with. The fields of the
+// first result set are available directly; ResultSets lists all of them.
+type templateResult struct {
+	templateResultSet
+	File         string
+	Line         int
+	Statement    string
+	RowsAffected int64
+	Duration     time.Duration
+	ResultSets   []templateResultSet
+}
+
+// templateResultSet exposes a result set to templates. Records holds each
+// row as a map from column name to value.
+type templateResultSet struct {
+	Columns     []string
+	ColumnTypes []database.ColumnType
+	Rows        [][]interface{}
+	Records     []map[string]interface{}
+	RowCount    int64
+	Truncated   bool
+}
+
+// templateFuncs are the helper functions available to templates
+var templateFuncs = template.FuncMap{
+	"value":    formatValue,
+	"padLeft":  templatePadLeft,
+	"padRight": templatePadRight,
+	"number":   templateNumber,
+	"csv":      templateCSV,
+	"json":     templateJSON,
+	"join":     strings.Join,
+}
+
+// SetTemplate loads the template file used by the template format
+func (f *Formatter) SetTemplate(path string) error {
+	tmpl, err := parseTemplate(path)
+	if err != nil {
+		return err
+	}
+	f.template = tmpl
+	return nil
+}
+
+// parseTemplate parses a template file with the template helper functions
+func parseTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// resultTemplate returns the template a result is rendered with: the file
+// named by the statement's @template hint, resolved against the directory of
+// the SQL file, or the template set with SetTemplate
+func (f *Formatter) resultTemplate(result *database.ExecutionResult) (*template.Template, error) {
+	path := database.ParseHints(result.Statement)["template"]
+	if path == "" {
+		if f.template == nil {
+			return nil, fmt.Errorf("template output needs a template: use --template or a -- @template hint")
+		}
+		return f.template, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(result.FileName), path)
+	}
+	if tmpl, ok := f.templates[path]; ok {
+		return tmpl, nil
+	}
+	tmpl, err := parseTemplate(path)
+	if err != nil {
+		return nil, err
+	}
+	if f.templates == nil {
+		f.templates = make(map[string]*template.Template)
+	}
+	f.templates[path] = tmpl
+	return tmpl, nil
+}
+
+// formatTemplate renders a result through its template
+func (f *Formatter) formatTemplate(result *database.ExecutionResult, sets []database.ResultSet) error {
+	tmpl, err := f.resultTemplate(result)
+	if err != nil {
+		return err
+	}
+
+	data := templateResult{
+		File:         result.FileName,
+		Line:         result.LineNumber,
+		Statement:    result.Statement,
+		RowsAffected: result.RowsAffected,
+		Duration:     result.Duration,
+	}
+	for _, set := range sets {
+		data.ResultSets = append(data.ResultSets, newTemplateResultSet(set))
+	}
+	if len(data.ResultSets) > 0 {
+		data.templateResultSet = data.ResultSets[0]
+	}
+	return f.executeTemplate(tmpl, data)
+}
+
+// newTemplateResultSet converts a result set for templates
+func newTemplateResultSet(set database.ResultSet) templateResultSet {
+	records := make([]map[string]interface{}, len(set.Rows))
+	for i, row := range set.Rows {
+		records[i] = make(map[string]interface{}, len(set.Columns))
+		for j, col := range set.Columns {
+			records[i][col] = rowValue(row, j)
+		}
+	}
+	return templateResultSet{
+		Columns:     set.Columns,
+		ColumnTypes: set.ColumnTypes,
+		Rows:        set.Rows,
+		Records:     records,
+		RowCount:    set.RowCount,
+		Truncated:   set.Truncated,
+	}
+}
+
+// formatDataAsTemplate renders generic data through the template set with
+// SetTemplate
+func (f *Formatter) formatDataAsTemplate(data []Record) error {
+	if f.template == nil {
+		return fmt.Errorf("template output needs a template: use --template")
+	}
+
+	columns, rows := dataRows(data)
+	set := newTemplateResultSet(database.ResultSet{Columns: columns, Rows: rows, RowCount: int64(len(rows))})
+	return f.executeTemplate(f.template, templateResult{
+		templateResultSet: set,
+		ResultSets:        []templateResultSet{set},
+	})
+}
+
+// executeTemplate writes the output of a template to the formatter's writer,
+// with the value function rendering values as the formatter's options say
+func (f *Formatter) executeTemplate(tmpl *template.Template, data templateResult) error {
+	tmpl.Funcs(template.FuncMap{"value": f.formatValue})
+	if err := tmpl.Execute(f.writer, data); err != nil {
+		return fmt.Errorf("error rendering template %s: %w", tmpl.Name(), err)
+	}
+	return nil
+}
+
+// templateText converts a value to text, formatting database values as the
+// other text formats do
+func templateText(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return s
+	}
+	return formatValue(val)
+}
+
+// templatePadLeft right-aligns a value in width characters
+func templatePadLeft(width int, val interface{}) string {
+	s := templateText(val)
+	if n := len([]rune(s)); n < width {
+		return strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
+
+// templatePadRight left-aligns a value in width characters
+func templatePadRight(width int, val interface{}) string {
+	return padRight(templateText(val), width)
+}
+
+// templateNumber formats a number with the given number of decimals and
+// commas between groups of thousands
+func templateNumber(decimals int, val interface{}) (string, error) {
+	decimals = max(decimals, 0)
+
+	var text string
+	switch v := val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		text = fmt.Sprintf("%d", v)
+		if decimals > 0 {
+			text += "." + strings.Repeat("0", decimals)
+		}
+	case float32:
+		text = strconv.FormatFloat(float64(v), 'f', decimals, 32)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', decimals, 64)
+	default:
+		number, err := strconv.ParseFloat(strings.TrimSpace(templateText(v)), 64)
+		if err != nil {
+			return "", fmt.Errorf("number: %q is not a number", templateText(v))
+		}
+		text = strconv.FormatFloat(number, 'f', decimals, 64)
+	}
+
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(text, ".")
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFraction {
+		b.WriteString("." + fraction)
+	}
+	return b.String(), nil
+}
+
+// templateCSV quotes a value as a CSV field, or a slice of values as a CSV
+// record, without the trailing newline
+func templateCSV(val interface{}) (string, error) {
+	var fields []string
+	switch v := val.(type) {
+	case []string:
+		fields = v
+	case []interface{}:
+		for _, item := range v {
+			fields = append(fields, templateText(item))
+		}
+	default:
+		fields = []string{templateText(v)}
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(fields); err != nil {
+		return "", err
+	}
+	writer.Flush()
+	return strings.TrimSuffix(buf.String(), "\n"), writer.Error()
+}
+
+// templateJSON encodes a value as compact JSON, converting database values
+// as the json format does
+func templateJSON(val interface{}) (string, error) {
+	data, err := json.Marshal(templateJSONValue(val))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// templateJSONValue applies jsonValue to a value and to the values of the
+// records and rows that templates pass around
+func templateJSONValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = jsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = jsonValue(item)
+		}
+		return converted
+	default:
+		return jsonValue(val)
+	}
+}
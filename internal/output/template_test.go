@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/database"
+)
+
+// writeTemplate writes a template file into dir and returns its path
+func writeTemplate(t *testing.T, dir, name, text string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	return path
+}
+
+func TestFormatTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTemplate(t, dir, "metrics.tmpl",
+		`# {{.File}}:{{.Line}} {{len .Columns}} columns
+{{range .Records}}table_rows{table="{{.name}}"} {{.rows}}
+{{end}}`)
+
+	var buf bytes.Buffer
+	formatter := NewFormatter("template", &buf)
+	if err := formatter.SetTemplate(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result := &database.ExecutionResult{
+		FileName:   "report.sql",
+		LineNumber: 3,
+		Columns:    []string{"name", "rows"},
+		Rows:       [][]interface{}{{"users", int64(42)}, {"orders", int64(7)}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "# report.sql:3 2 columns\ntable_rows{table=\"users\"} 42\ntable_rows{table=\"orders\"} 7\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected template output:\n%q\nexpected:\n%q", buf.String(), expected)
+	}
+}
+
+func TestFormatTemplateRowsAffected(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTemplate(t, dir, "dml.tmpl", "{{.Statement}}: {{.RowsAffected}} rows\n")
+
+	var buf bytes.Buffer
+	formatter := NewFormatter("template", &buf)
+	if err := formatter.SetTemplate(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	result := &database.ExecutionResult{Statement: "DELETE FROM t", RowsAffected: 5}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.String() != "DELETE FROM t: 5 rows\n" {
+		t.Errorf("Unexpected template output: %q", buf.String())
+	}
+}
+
+func TestFormatTemplateHint(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "names.tmpl", "{{range .Rows}}{{csv .}}\n{{end}}")
+
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+	result := &database.ExecutionResult{
+		FileName:  filepath.Join(dir, "report.sql"),
+		Statement: "-- @template names.tmpl\nSELECT name, note FROM t",
+		Columns:   []string{"name", "note"},
+		Rows:      [][]interface{}{{"a", "x, y"}, {"b", nil}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if buf.String() != "a,\"x, y\"\nb,NULL\n" {
+		t.Errorf("Unexpected template output: %q", buf.String())
+	}
+
+	// Without a template file the template format cannot render anything
+	result.Statement = "-- @output template\nSELECT name, note FROM t"
+	if err := formatter.FormatResult(result); err == nil || !strings.Contains(err.Error(), "--template") {
+		t.Errorf("Expected an error naming --template, got %v", err)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       func() (string, error)
+		expected string
+	}{
+		{"number int", func() (string, error) { return templateNumber(0, int64(1234567)) }, "1,234,567"},
+		{"number float", func() (string, error) { return templateNumber(2, -1234.5) }, "-1,234.50"},
+		{"number decimal", func() (string, error) { return templateNumber(1, database.Decimal("999.95")) }, "1,000.0"},
+		{"number small", func() (string, error) { return templateNumber(0, int64(12)) }, "12"},
+		{"csv field", func() (string, error) { return templateCSV(`say "hi"`) }, `"say ""hi"""`},
+		{"csv record", func() (string, error) { return templateCSV([]string{"a", "b,c"}) }, `a,"b,c"`},
+		{"json record", func() (string, error) {
+			return templateJSON(map[string]interface{}{"amount": database.Decimal("1.50"), "name": "x"})
+		}, `{"amount":1.50,"name":"x"}`},
+		{"pad left", func() (string, error) { return templatePadLeft(5, int64(42)), nil }, "   42"},
+		{"pad right", func() (string, error) { return templatePadRight(5, "ab") + "|", nil }, "ab   |"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Got %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := templateNumber(0, "abc"); err == nil {
+		t.Error("Expected an error for a value that is not a number")
+	}
+}
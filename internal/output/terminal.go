@@ -0,0 +1,35 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// terminalWidth returns the width in columns of the terminal w writes to, or
+// 0 when w is not a terminal. The COLUMNS environment variable overrides the
+// detected width.
+func terminalWidth(w io.Writer) int {
+	file, ok := terminalFile(w)
+	if !ok {
+		return 0
+	}
+
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return fileTerminalWidth(file)
+}
+
+// terminalFile returns the file w writes to when it is a terminal
+func terminalFile(w io.Writer) (*os.File, bool) {
+	file, ok := w.(*os.File)
+	if !ok {
+		return nil, false
+	}
+	info, err := file.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil, false
+	}
+	return file, true
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package output
+
+import "os"
+
+// fileTerminalWidth is not supported on this platform; only COLUMNS is used
+func fileTerminalWidth(file *os.File) int {
+	return 0
+}
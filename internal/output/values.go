@@ -0,0 +1,110 @@
+package output
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueOptions control how values are rendered by the text formats: table,
+// vertical, auto, csv, markdown, html, asciidoc and the template value
+// function. Structured formats such as json keep their native types. The
+// zero value renders values as formatValue does.
+type ValueOptions struct {
+	// NullDisplay replaces NULL values; nil keeps the format's default,
+	// which is NULL, or an empty field in csv
+	NullDisplay *string
+	// FloatFormat is g, f or e as in strconv.FormatFloat, and FloatPrecision
+	// the number of digits, nil for the fewest that represent the value. A
+	// precision without a format means fixed-point notation.
+	FloatFormat    string
+	FloatPrecision *int
+	// TimeFormat is a Go time layout or one of the names in timeFormats;
+	// TimeZone converts times before they are formatted, nil keeps them as
+	// the driver returned them
+	TimeFormat string
+	TimeZone   *time.Location
+	// BoolFormat is the text for true and false, separated by a slash
+	BoolFormat string
+}
+
+// timeFormats are the time layouts that TimeFormat can name
+var timeFormats = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"datetime":    time.DateTime,
+	"date":        time.DateOnly,
+	"time":        time.TimeOnly,
+}
+
+// SetValueOptions sets how values are rendered by the text formats
+func (f *Formatter) SetValueOptions(options ValueOptions) {
+	f.values = options
+}
+
+// formatValue converts a database value to text with the value options
+func (f *Formatter) formatValue(val interface{}) string {
+	return f.values.text(val, "NULL")
+}
+
+// formatCSVValue converts a database value to a CSV field with the value
+// options, leaving NULL fields empty unless NullDisplay is set
+func (f *Formatter) formatCSVValue(val interface{}) string {
+	return f.values.text(val, "")
+}
+
+// text renders a value, using null for NULL values unless NullDisplay is set
+func (o ValueOptions) text(val interface{}, null string) string {
+	switch v := val.(type) {
+	case nil:
+		if o.NullDisplay != nil {
+			return *o.NullDisplay
+		}
+		return null
+	case float32:
+		return o.floatText(float64(v), 32)
+	case float64:
+		return o.floatText(v, 64)
+	case time.Time:
+		return o.timeText(v)
+	case bool:
+		if trueText, falseText, ok := strings.Cut(o.BoolFormat, "/"); ok {
+			if v {
+				return trueText
+			}
+			return falseText
+		}
+	}
+	return formatValue(val)
+}
+
+// floatText renders a float with the float format and precision
+func (o ValueOptions) floatText(v float64, bitSize int) string {
+	format := o.FloatFormat
+	precision := -1
+	if o.FloatPrecision != nil {
+		precision = *o.FloatPrecision
+		if format == "" {
+			format = "f"
+		}
+	}
+	if format == "" {
+		format = "g"
+	}
+	return strconv.FormatFloat(v, format[0], precision, bitSize)
+}
+
+// timeText renders a time in the time zone and format of the options
+func (o ValueOptions) timeText(t time.Time) string {
+	if o.TimeZone != nil {
+		t = t.In(o.TimeZone)
+	}
+	layout := time.RFC3339Nano
+	if o.TimeFormat != "" {
+		layout = o.TimeFormat
+		if named, ok := timeFormats[strings.ToLower(o.TimeFormat)]; ok {
+			layout = named
+		}
+	}
+	return t.Format(layout)
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gosqlpp/internal/database"
+)
+
+func TestValueOptionsText(t *testing.T) {
+	dash := "-"
+	two := 2
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	moment := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		options  ValueOptions
+		input    interface{}
+		expected string
+	}{
+		{"default null", ValueOptions{}, nil, "NULL"},
+		{"null display", ValueOptions{NullDisplay: &dash}, nil, "-"},
+		{"default float", ValueOptions{}, 1234567.5, "1.2345675e+06"},
+		{"float precision", ValueOptions{FloatPrecision: &two}, 3.14159, "3.14"},
+		{"float format", ValueOptions{FloatFormat: "f"}, 1234567.5, "1234567.5"},
+		{"exponent format", ValueOptions{FloatFormat: "e", FloatPrecision: &two}, 1234.5, "1.23e+03"},
+		{"float32", ValueOptions{FloatPrecision: &two}, float32(0.5), "0.50"},
+		{"default time", ValueOptions{}, moment, "2024-03-01T12:30:00Z"},
+		{"named time format", ValueOptions{TimeFormat: "datetime"}, moment, "2024-03-01 12:30:00"},
+		{"time layout", ValueOptions{TimeFormat: "02/01/2006"}, moment, "01/03/2024"},
+		{"time zone", ValueOptions{TimeZone: paris}, moment, "2024-03-01T13:30:00+01:00"},
+		{"default bool", ValueOptions{}, true, "true"},
+		{"bool format true", ValueOptions{BoolFormat: "yes/no"}, true, "yes"},
+		{"bool format false", ValueOptions{BoolFormat: "yes/no"}, false, "no"},
+		{"other values", ValueOptions{BoolFormat: "yes/no"}, int64(42), "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.options.text(tt.input, "NULL"); got != tt.expected {
+				t.Errorf("text(%v) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCSVValueDefaults(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("csv", &buf)
+	result := &database.ExecutionResult{
+		Columns: []string{"id", "note"},
+		Rows:    [][]interface{}{{int64(1), nil}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expected := "id,note\n1,\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	null := "NULL"
+	formatter.SetValueOptions(ValueOptions{NullDisplay: &null})
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if expected := "id,note\n1,NULL\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestValueOptionsInTable(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := NewFormatter("table", &buf)
+	empty := ""
+	formatter.SetValueOptions(ValueOptions{NullDisplay: &empty, BoolFormat: "Y/N"})
+	result := &database.ExecutionResult{
+		Columns: []string{"name", "active"},
+		Rows:    [][]interface{}{{nil, true}},
+	}
+	if err := formatter.FormatResult(result); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if output := buf.String(); strings.Contains(output, "NULL") || !strings.Contains(output, "Y") {
+		t.Errorf("Expected value options to apply to the table, got:\n%s", output)
+	}
+}
@@ -0,0 +1,148 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gosqlpp/internal/database"
+)
+
+// tablePadding is the space rodaine/table puts between columns
+const tablePadding = 2
+
+// formatVertical prints each row as a block of column | value pairs, like
+// psql's expanded display or MySQL's \G
+func (f *Formatter) formatVertical(sets []database.ResultSet) error {
+	for i, set := range sets {
+		if len(sets) > 1 {
+			if i > 0 {
+				fmt.Fprintln(f.writer)
+			}
+			fmt.Fprintf(f.writer, "Result set %d:\n", i+1)
+		}
+
+		f.writeVerticalRows(set.Columns, set.Rows)
+		fmt.Fprintf(f.writer, "(%d rows)\n", set.RowCount)
+	}
+	return nil
+}
+
+// writeVerticalRows writes rows as -[ RECORD n ]- blocks
+func (f *Formatter) writeVerticalRows(columns []string, rows [][]interface{}) {
+	nameWidth := 0
+	for _, col := range columns {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(col))
+	}
+
+	for n, row := range rows {
+		// Values spanning several lines continue below an empty column name
+		values := make([][]string, len(columns))
+		valueWidth := 0
+		for i := range columns {
+			var val interface{}
+			if i < len(row) {
+				val = row[i]
+			}
+			values[i] = strings.Split(f.formatValue(val), "\n")
+			for _, line := range values[i] {
+				valueWidth = max(valueWidth, utf8.RuneCountInString(line))
+			}
+		}
+
+		header := fmt.Sprintf("-[ RECORD %d ]", n+1)
+		if fill := nameWidth + 1 - utf8.RuneCountInString(header); fill > 0 {
+			header += strings.Repeat("-", fill)
+		}
+		fmt.Fprintf(f.writer, "%s+%s\n", header, strings.Repeat("-", valueWidth+1))
+
+		for i, col := range columns {
+			for j, line := range values[i] {
+				name := col
+				if j > 0 {
+					name = ""
+				}
+				fmt.Fprintf(f.writer, "%s | %s\n", padRight(name, nameWidth), line)
+			}
+		}
+	}
+	if len(rows) > 0 {
+		fmt.Fprintln(f.writer)
+	}
+}
+
+// formatAuto prints result sets as tables, switching to the vertical format
+// when a table would not fit the terminal
+func (f *Formatter) formatAuto(sets []database.ResultSet) error {
+	width := f.outputWidth()
+	for _, set := range sets {
+		if width > 0 && f.tableWidth(set.Columns, set.Rows) > width {
+			return f.formatVertical(sets)
+		}
+	}
+	return f.formatTable(sets)
+}
+
+// outputWidth returns the width available for output, or 0 when unknown
+func (f *Formatter) outputWidth() int {
+	if f.width > 0 {
+		return f.width
+	}
+	return terminalWidth(f.writer)
+}
+
+// SetWidth sets the output width used by the auto format instead of the
+// terminal width
+func (f *Formatter) SetWidth(width int) {
+	f.width = width
+}
+
+// tableWidth returns the width of the widest line of a table
+func (f *Formatter) tableWidth(columns []string, rows [][]interface{}) int {
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	for _, row := range rows {
+		for i := range columns {
+			if i < len(row) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(f.formatValue(row[i])))
+			}
+		}
+	}
+	if f.maxColWidth > 0 {
+		for i := range widths {
+			widths[i] = min(widths[i], f.maxColWidth)
+		}
+	}
+
+	total := 0
+	for _, w := range widths {
+		total += w + tablePadding
+	}
+	return total
+}
+
+// padRight pads s with spaces to width runes
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// formatDataAsVertical formats generic data in the vertical format
+func (f *Formatter) formatDataAsVertical(data []Record) error {
+	columns, rows := dataRows(data)
+	f.writeVerticalRows(columns, rows)
+	return nil
+}
+
+// formatDataAsAuto formats generic data as a table, or vertically when the
+// table would not fit the terminal
+func (f *Formatter) formatDataAsAuto(data []Record) error {
+	if width := f.outputWidth(); width > 0 && f.tableWidth(dataRows(data)) > width {
+		return f.formatDataAsVertical(data)
+	}
+	return f.formatDataAsTable(data)
+}
@@ -0,0 +1,409 @@
+package output
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gosqlpp/internal/database"
+)
+
+// Limits of the xlsx format and of the automatic column widths
+const (
+	xlsxMaxSheetName = 31
+	xlsxMaxCellText  = 32767
+	xlsxMinWidth     = 8
+	xlsxMaxWidth     = 60
+)
+
+// Cell styles defined by writeXLSXStyles
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeader
+	xlsxStyleDate
+	xlsxStyleDateTime
+)
+
+// xlsxSheet is a worksheet collected for the xlsx workbook
+type xlsxSheet struct {
+	name        string
+	columns     []string
+	columnTypes []database.ColumnType
+	rows        [][]interface{}
+}
+
+// xlsxPart is a file of the xlsx package and the function writing it
+type xlsxPart struct {
+	name  string
+	write func(io.Writer) error
+}
+
+// xlsxTimeLayouts are the textual date/time formats read from string values
+// of date and time columns
+var xlsxTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// xlsxEpoch is day zero of Excel's date serial numbers
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// addSheets adds one worksheet per result set to the workbook, named after
+// the statement's @sheet hint or its position in the run
+func (f *Formatter) addSheets(result *database.ExecutionResult, sets []database.ResultSet) {
+	f.sheetStatements++
+	name := database.ParseHints(result.Statement)["sheet"]
+	if name == "" {
+		name = fmt.Sprintf("Statement %d", f.sheetStatements)
+	}
+
+	for i, set := range sets {
+		sheetName := name
+		if i > 0 {
+			sheetName = fmt.Sprintf("%s (%d)", name, i+1)
+		}
+		f.sheets = append(f.sheets, xlsxSheet{
+			name:        f.uniqueSheetName(sheetName),
+			columns:     set.Columns,
+			columnTypes: set.ColumnTypes,
+			rows:        set.Rows,
+		})
+	}
+}
+
+// formatDataAsXLSX adds generic data to the workbook as a worksheet
+func (f *Formatter) formatDataAsXLSX(data []Record) error {
+	f.sheetStatements++
+	columns, rows := dataRows(data)
+	f.sheets = append(f.sheets, xlsxSheet{
+		name:    f.uniqueSheetName(fmt.Sprintf("Statement %d", f.sheetStatements)),
+		columns: columns,
+		rows:    rows,
+	})
+	return nil
+}
+
+// uniqueSheetName makes a valid sheet name that no other sheet of the
+// workbook has: at most 31 characters, without the characters Excel
+// reserves, and unique regardless of case
+func (f *Formatter) uniqueSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Sheet"
+	}
+
+	taken := func(candidate string) bool {
+		for _, sheet := range f.sheets {
+			if strings.EqualFold(sheet.name, candidate) {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := truncateRunes(name, xlsxMaxSheetName)
+	for n := 2; taken(candidate); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate = truncateRunes(name, xlsxMaxSheetName-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// writeWorkbook writes the collected worksheets as an xlsx workbook
+func (f *Formatter) writeWorkbook() error {
+	sheets := f.sheets
+	f.sheets = nil
+	if len(sheets) == 0 {
+		// A workbook needs at least one sheet
+		sheets = []xlsxSheet{{name: "Sheet1"}}
+	}
+
+	parts := []xlsxPart{
+		{"[Content_Types].xml", func(w io.Writer) error { return writeXLSXContentTypes(w, len(sheets)) }},
+		{"_rels/.rels", writeXLSXRootRels},
+		{"xl/workbook.xml", func(w io.Writer) error { return writeXLSXWorkbook(w, sheets) }},
+		{"xl/_rels/workbook.xml.rels", func(w io.Writer) error { return writeXLSXWorkbookRels(w, len(sheets)) }},
+		{"xl/styles.xml", writeXLSXStyles},
+	}
+	for i, sheet := range sheets {
+		parts = append(parts, xlsxPart{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.write})
+	}
+
+	archive := zip.NewWriter(f.writer)
+	for _, part := range parts {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("error writing xlsx workbook: %w", err)
+		}
+		if err := part.write(w); err != nil {
+			return fmt.Errorf("error writing xlsx workbook: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error writing xlsx workbook: %w", err)
+	}
+	return nil
+}
+
+// writeXLSXContentTypes writes the content types of the workbook parts
+func writeXLSXContentTypes(w io.Writer, sheetCount int) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeXLSXRootRels writes the package relationship to the workbook
+func writeXLSXRootRels(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header+
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>`+
+		`</Relationships>`)
+	return err
+}
+
+// writeXLSXWorkbook writes the workbook part listing the sheets
+func writeXLSXWorkbook(w io.Writer, sheets []xlsxSheet) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeXML(sheet.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeXLSXWorkbookRels writes the relationships from the workbook to its
+// sheets and styles
+func writeXLSXWorkbookRels(w io.Writer, sheetCount int) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheetCount+1)
+	b.WriteString(`</Relationships>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeXLSXStyles writes the cell styles: default, bold header, date and
+// date with time
+func writeXLSXStyles(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header+
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>`+
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`+
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>`+
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`+
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`+
+		`<cellXfs count="4">`+
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`+
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>`+
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`+
+		`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`+
+		`</cellXfs>`+
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>`+
+		`</styleSheet>`)
+	return err
+}
+
+// write writes the worksheet: a frozen bold header row, the data rows with
+// native cell types, and column widths fitted to the content
+func (s xlsxSheet) write(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.columns) > 0 {
+		b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+		b.WriteString(`<cols>`)
+		for i, width := range s.columnWidths() {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	if len(s.columns) > 0 {
+		b.WriteString(`<row r="1">`)
+		for i, col := range s.columns {
+			writeXLSXString(&b, cellReference(i, 1), col, xlsxStyleHeader)
+		}
+		b.WriteString(`</row>`)
+	}
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+2)
+		for i := range s.columns {
+			s.writeCell(&b, cellReference(i, r+2), i, rowValue(row, i))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCell writes a value as a cell of its native type. NULL values are
+// left out, leaving the cell empty.
+func (s xlsxSheet) writeCell(b *strings.Builder, ref string, col int, val interface{}) {
+	switch v := val.(type) {
+	case nil:
+	case bool:
+		n := 0
+		if v {
+			n = 1
+		}
+		fmt.Fprintf(b, `<c r="%s" t="b"><v>%d</v></c>`, ref, n)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprintf(b, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case float32:
+		writeXLSXFloat(b, ref, float64(v))
+	case float64:
+		writeXLSXFloat(b, ref, v)
+	case database.Decimal:
+		if isJSONNumber(string(v)) {
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, v)
+			return
+		}
+		writeXLSXString(b, ref, string(v), xlsxStyleDefault)
+	case time.Time:
+		s.writeTime(b, ref, col, v)
+	case string:
+		// Dates that reach us as text still get a date cell
+		if s.isTimeColumn(col) {
+			for _, layout := range xlsxTimeLayouts {
+				if t, err := time.Parse(layout, v); err == nil {
+					s.writeTime(b, ref, col, t)
+					return
+				}
+			}
+		}
+		writeXLSXString(b, ref, v, xlsxStyleDefault)
+	default:
+		writeXLSXString(b, ref, formatValue(v), xlsxStyleDefault)
+	}
+}
+
+// writeTime writes a time as a date serial number, formatted as a date for
+// DATE columns and as a date and time otherwise
+func (s xlsxSheet) writeTime(b *strings.Builder, ref string, col int, t time.Time) {
+	// Excel has no time zones, so the wall clock time is kept
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	serial := wall.Sub(xlsxEpoch).Hours() / 24
+
+	style := xlsxStyleDateTime
+	if s.columnTypeName(col) == "DATE" {
+		style = xlsxStyleDate
+	}
+	fmt.Fprintf(b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(serial, 'f', -1, 64))
+}
+
+// columnTypeName returns the upper-cased database type of a column, or an
+// empty string when it is unknown
+func (s xlsxSheet) columnTypeName(col int) string {
+	if col < len(s.columnTypes) {
+		return strings.ToUpper(s.columnTypes[col].DatabaseType)
+	}
+	return ""
+}
+
+// isTimeColumn reports whether a column holds dates or times
+func (s xlsxSheet) isTimeColumn(col int) bool {
+	name := s.columnTypeName(col)
+	return strings.Contains(name, "DATE") || strings.Contains(name, "TIMESTAMP")
+}
+
+// columnWidths fits the width of each column to its header and values
+func (s xlsxSheet) columnWidths() []int {
+	widths := make([]int, len(s.columns))
+	for i, col := range s.columns {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+	for _, row := range s.rows {
+		for i := range s.columns {
+			val := rowValue(row, i)
+			width := utf8.RuneCountInString(formatValue(val))
+			if _, ok := val.(time.Time); ok {
+				width = len("2006-01-02 15:04:05")
+			}
+			widths[i] = max(widths[i], width)
+		}
+	}
+	for i := range widths {
+		widths[i] = min(max(widths[i]+2, xlsxMinWidth), xlsxMaxWidth)
+	}
+	return widths
+}
+
+// writeXLSXFloat writes a float as a number cell, or as text when it has no
+// numeric value in Excel
+func writeXLSXFloat(b *strings.Builder, ref string, v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		writeXLSXString(b, ref, strconv.FormatFloat(v, 'g', -1, 64), xlsxStyleDefault)
+		return
+	}
+	fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+// writeXLSXString writes an inline string cell, cut to the longest text a
+// cell can hold
+func writeXLSXString(b *strings.Builder, ref, text string, style int) {
+	text = truncateRunes(text, xlsxMaxCellText)
+	styleAttr := ""
+	if style != xlsxStyleDefault {
+		styleAttr = fmt.Sprintf(` s="%d"`, style)
+	}
+	fmt.Fprintf(b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr, escapeXML(text))
+}
+
+// escapeXML escapes text for XML content and attributes
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// cellReference returns the A1-style reference of a zero-based column and a
+// one-based row
+func cellReference(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row)
+}
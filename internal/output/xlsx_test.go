@@ -0,0 +1,200 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"gosqlpp/internal/database"
+)
+
+// xlsxTestSheet is the part of a worksheet the tests read back
+type xlsxTestSheet struct {
+	Cols []struct {
+		Min   int     `xml:"min,attr"`
+		Width float64 `xml:"width,attr"`
+	} `xml:"cols>col"`
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Style  int    `xml:"s,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readWorkbook reads the sheet names and worksheets of an xlsx workbook
+func readWorkbook(t *testing.T, data []byte) ([]string, []xlsxTestSheet) {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Expected a zip archive, got %v", err)
+	}
+
+	parts := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file.Name, err)
+		}
+		parts[file.Name] = content
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Expected part %s in the workbook", name)
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatalf("Failed to parse workbook: %v", err)
+	}
+
+	var names []string
+	var sheets []xlsxTestSheet
+	for i, sheet := range workbook.Sheets {
+		names = append(names, sheet.Name)
+		var worksheet xlsxTestSheet
+		name := "xl/worksheets/sheet" + string(rune('1'+i)) + ".xml"
+		if err := xml.Unmarshal(parts[name], &worksheet); err != nil {
+			t.Fatalf("Failed to parse %s: %v", name, err)
+		}
+		sheets = append(sheets, worksheet)
+	}
+	return names, sheets
+}
+
+func TestFormatXLSX(t *testing.T) {
+	var buf, messages bytes.Buffer
+	formatter := NewFormatter("xlsx", &buf)
+	formatter.messages = &messages
+
+	results := []*database.ExecutionResult{
+		{
+			Statement: "-- @sheet Orders\nSELECT * FROM orders",
+			Columns:   []string{"id", "customer", "total", "paid", "ordered_on", "shipped_at"},
+			ColumnTypes: []database.ColumnType{
+				{Name: "id", DatabaseType: "INTEGER"},
+				{Name: "customer", DatabaseType: "TEXT"},
+				{Name: "total", DatabaseType: "DECIMAL"},
+				{Name: "paid", DatabaseType: "BOOLEAN"},
+				{Name: "ordered_on", DatabaseType: "DATE"},
+				{Name: "shipped_at", DatabaseType: "TIMESTAMP"},
+			},
+			Rows: [][]interface{}{
+				{int64(1), "Ada & Co", database.Decimal("19.99"), true, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "2024-01-03 12:00:00"},
+				{int64(2), nil, database.Decimal("5"), false, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), nil},
+			},
+		},
+		{
+			Statement: "SELECT 1 AS n",
+			Columns:   []string{"n"},
+			Rows:      [][]interface{}{{int64(1)}},
+		},
+		{Statement: "UPDATE t SET x = 1", RowsAffected: 3},
+	}
+	for _, result := range results {
+		if err := formatter.FormatResult(result); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Fatal("Expected the workbook to be written on Close")
+	}
+	if err := formatter.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	names, sheets := readWorkbook(t, buf.Bytes())
+	if len(names) != 2 || names[0] != "Orders" || names[1] != "Statement 2" {
+		t.Fatalf("Expected sheets [Orders Statement 2], got %v", names)
+	}
+	if messages.String() != "(3 rows affected)\n" {
+		t.Errorf("Expected messages to go to the message writer, got %q", messages.String())
+	}
+
+	orders := sheets[0]
+	if len(orders.Rows) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d rows", len(orders.Rows))
+	}
+	for _, cell := range orders.Rows[0].Cells {
+		if cell.Style != xlsxStyleHeader || cell.Type != "inlineStr" {
+			t.Errorf("Expected a bold header cell, got %+v", cell)
+		}
+	}
+
+	cells := orders.Rows[1].Cells
+	expected := []struct {
+		ref, typ, value string
+		style           int
+	}{
+		{"A2", "", "1", xlsxStyleDefault},
+		{"B2", "inlineStr", "Ada & Co", xlsxStyleDefault},
+		{"C2", "", "19.99", xlsxStyleDefault},
+		{"D2", "b", "1", xlsxStyleDefault},
+		{"E2", "", "45293", xlsxStyleDate},
+		{"F2", "", "45294.5", xlsxStyleDateTime},
+	}
+	if len(cells) != len(expected) {
+		t.Fatalf("Expected %d cells, got %d", len(expected), len(cells))
+	}
+	for i, want := range expected {
+		cell := cells[i]
+		value := cell.Value
+		if cell.Type == "inlineStr" {
+			value = cell.Inline
+		}
+		if cell.Ref != want.ref || cell.Type != want.typ || value != want.value || cell.Style != want.style {
+			t.Errorf("Cell %d = %+v, expected %+v", i, cell, want)
+		}
+	}
+
+	// NULL values leave the cell empty
+	if len(orders.Rows[2].Cells) != 4 {
+		t.Errorf("Expected NULL cells to be left out, got %+v", orders.Rows[2].Cells)
+	}
+
+	// Widths fit the content: "customer" is the widest value of its column
+	if len(orders.Cols) != 6 || orders.Cols[1].Width != float64(len("customer")+2) {
+		t.Errorf("Unexpected column widths: %+v", orders.Cols)
+	}
+}
+
+func TestUniqueSheetName(t *testing.T) {
+	formatter := NewFormatter("xlsx", io.Discard)
+	formatter.sheets = []xlsxSheet{{name: "Report"}}
+
+	tests := map[string]string{
+		"report":                                "report (2)",
+		"Q1/Q2: sales?":                         "Q1_Q2_ sales_",
+		"A very long sheet name that overflows": "A very long sheet name that ove",
+	}
+	for name, expected := range tests {
+		if got := formatter.uniqueSheetName(name); got != expected {
+			t.Errorf("uniqueSheetName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestCellReference(t *testing.T) {
+	tests := map[int]string{0: "A1", 25: "Z1", 26: "AA1", 701: "ZZ1", 702: "AAA1"}
+	for col, expected := range tests {
+		if got := cellReference(col, 1); got != expected {
+			t.Errorf("cellReference(%d, 1) = %q, expected %q", col, got, expected)
+		}
+	}
+}
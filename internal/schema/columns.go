@@ -0,0 +1,254 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gosqlpp/internal/output"
+
+	"github.com/jimsmart/schema"
+)
+
+// columnCatalog holds the nullability and default of a column as recorded
+// in the database catalog
+type columnCatalog struct {
+	nullable     bool
+	defaultValue *string
+}
+
+// processDescribe processes @describe command, listing the columns of one
+// table or view
+func (i *Introspector) processDescribe(table string) error {
+	if table == "" {
+		return fmt.Errorf("@describe requires a table name")
+	}
+
+	name, err := i.findObject(table)
+	if err != nil {
+		return err
+	}
+
+	columns, err := i.describeColumns(name)
+	if err != nil {
+		return err
+	}
+
+	return i.formatSchemaResults(columns)
+}
+
+// processSchemaColumns processes @schema-columns command, listing the
+// columns of every table matching the filter
+func (i *Introspector) processSchemaColumns(filter string) error {
+	tables, err := i.tableNames()
+	if err != nil {
+		return err
+	}
+
+	// Filter tables if filter is provided
+	if filter != "" {
+		tables = filterNames(tables, filter)
+	}
+
+	var columnInfo []output.Record
+	for _, tableName := range tables {
+		columns, err := i.describeColumns(tableName)
+		if err != nil {
+			return err
+		}
+		for _, column := range columns {
+			info := output.Record{{Key: "table_name", Value: tableName}}
+			columnInfo = append(columnInfo, append(info, column...))
+		}
+	}
+
+	if len(columnInfo) == 0 {
+		return i.formatter.WriteMessage("No columns found")
+	}
+
+	return i.formatSchemaResults(columnInfo)
+}
+
+// tableNames retrieves the names of the tables of the current schema
+func (i *Introspector) tableNames() ([]string, error) {
+	tableNames, err := schema.TableNames(i.connection.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve table names: %w", err)
+	}
+	return objectNames(tableNames), nil
+}
+
+// findObject returns the name of the table or view matching name, ignoring
+// case, as it is spelled in the database
+func (i *Introspector) findObject(name string) (string, error) {
+	tables, err := i.tableNames()
+	if err != nil {
+		return "", err
+	}
+	viewNames, err := schema.ViewNames(i.connection.DB)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve view names: %w", err)
+	}
+
+	for _, object := range append(tables, objectNames(viewNames)...) {
+		if strings.EqualFold(object, name) {
+			return object, nil
+		}
+	}
+	return "", fmt.Errorf("table or view not found: %s", name)
+}
+
+// objectNames converts the [schema, name] pairs of jimsmart/schema to
+// names. For SQLite the schema is empty.
+func objectNames(objects [][2]string) []string {
+	var names []string
+	for _, object := range objects {
+		name := object[1]
+		if name == "" {
+			name = object[0]
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// describeColumns returns a record for each column of a table or view with
+// its ordinal, name, type, nullability, default, primary key membership,
+// length, precision and scale. Values the driver does not report are nil.
+func (i *Introspector) describeColumns(table string) ([]output.Record, error) {
+	columns, err := schema.ColumnTypes(i.connection.DB, "", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve columns of %s: %w", table, err)
+	}
+
+	catalog, err := i.columnCatalog(table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve column details of %s: %w", table, err)
+	}
+
+	// Views have no primary key, and some drivers fail to look one up
+	primaryKey, _ := schema.PrimaryKey(i.connection.DB, "", table)
+	inPrimaryKey := make(map[string]bool, len(primaryKey))
+	for _, name := range primaryKey {
+		inPrimaryKey[strings.ToLower(name)] = true
+	}
+
+	var records []output.Record
+	for n, col := range columns {
+		typeName := col.DatabaseTypeName()
+		length, precision, scale := columnSize(col)
+
+		var nullable, defaultValue interface{}
+		if details, ok := catalog[strings.ToLower(col.Name())]; ok {
+			nullable = details.nullable
+			if details.defaultValue != nil {
+				defaultValue = *details.defaultValue
+			}
+		} else if isNullable, ok := col.Nullable(); ok {
+			nullable = isNullable
+		}
+
+		records = append(records, output.Record{
+			{Key: "ordinal", Value: n + 1},
+			{Key: "column_name", Value: col.Name()},
+			{Key: "type", Value: typeName},
+			{Key: "nullable", Value: nullable},
+			{Key: "default", Value: defaultValue},
+			{Key: "primary_key", Value: inPrimaryKey[strings.ToLower(col.Name())]},
+			{Key: "length", Value: length},
+			{Key: "precision", Value: precision},
+			{Key: "scale", Value: scale},
+		})
+	}
+
+	return records, nil
+}
+
+// columnSize returns the length, precision and scale of a column, or nil
+// for those that do not apply. When the driver does not report them, as
+// with SQLite, they are read from the declared type, such as VARCHAR(50)
+// or DECIMAL(10,2).
+func columnSize(col *sql.ColumnType) (length, precision, scale interface{}) {
+	if n, ok := col.Length(); ok {
+		length = n
+	}
+	if p, s, ok := col.DecimalSize(); ok {
+		precision, scale = p, s
+	}
+	if length != nil || precision != nil {
+		return length, precision, scale
+	}
+
+	typeName := col.DatabaseTypeName()
+	open := strings.Index(typeName, "(")
+	if open < 0 || !strings.HasSuffix(typeName, ")") {
+		return nil, nil, nil
+	}
+	var sizes []int64
+	for _, part := range strings.Split(typeName[open+1:len(typeName)-1], ",") {
+		size, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, nil, nil
+		}
+		sizes = append(sizes, size)
+	}
+
+	baseType := strings.ToUpper(strings.TrimSpace(typeName[:open]))
+	switch {
+	case strings.Contains(baseType, "DEC") || strings.Contains(baseType, "NUMERIC"):
+		precision = sizes[0]
+		scale = int64(0)
+		if len(sizes) > 1 {
+			scale = sizes[1]
+		}
+	case len(sizes) == 1:
+		length = sizes[0]
+	}
+	return length, precision, scale
+}
+
+// columnCatalog retrieves the nullability and default of the columns of a
+// table from the driver's catalog, keyed by lower case column name
+func (i *Introspector) columnCatalog(table string) (map[string]columnCatalog, error) {
+	var query string
+
+	switch i.connection.Driver {
+	case "sqlite3":
+		query = `SELECT name, CASE WHEN "notnull" = 0 THEN 'YES' ELSE 'NO' END, dflt_value
+				FROM pragma_table_info(?)`
+	case "postgres":
+		query = `SELECT column_name, is_nullable, column_default FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = $1`
+	case "mysql":
+		query = `SELECT column_name, is_nullable, column_default FROM information_schema.columns
+				WHERE table_schema = DATABASE() AND table_name = ?`
+	case "sqlserver":
+		query = `SELECT column_name, is_nullable, column_default FROM information_schema.columns
+				WHERE table_schema = SCHEMA_NAME() AND table_name = @p1`
+	default:
+		return nil, nil
+	}
+
+	rows, err := i.connection.DB.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalog := make(map[string]columnCatalog)
+	for rows.Next() {
+		var name, nullable string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&name, &nullable, &defaultValue); err != nil {
+			return nil, err
+		}
+		details := columnCatalog{nullable: strings.EqualFold(nullable, "YES")}
+		if defaultValue.Valid {
+			details.defaultValue = &defaultValue.String
+		}
+		catalog[strings.ToLower(name)] = details
+	}
+
+	return catalog, rows.Err()
+}
@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/config"
+	"gosqlpp/internal/database"
+	"gosqlpp/internal/output"
+)
+
+// newTestIntrospector opens an in-memory sqlite database, runs the setup
+// statements on it and returns an introspector writing to buf
+func newTestIntrospector(t *testing.T, format string, buf *bytes.Buffer, setup ...string) *Introspector {
+	t.Helper()
+
+	manager := database.NewManager()
+	t.Cleanup(func() { manager.CloseAll() })
+
+	connConfig := config.Connection{
+		Driver:           "sqlite3",
+		ConnectionString: ":memory:",
+	}
+	if err := manager.Connect("test", connConfig); err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	conn, err := manager.GetConnection("test")
+	if err != nil {
+		t.Fatalf("Failed to get test connection: %v", err)
+	}
+	// Keep a single connection so the in-memory database survives between statements
+	conn.DB.SetMaxOpenConns(1)
+
+	for _, stmt := range setup {
+		if _, err := conn.DB.Exec(stmt); err != nil {
+			t.Fatalf("Setup statement failed: %v", err)
+		}
+	}
+	return NewIntrospector(conn, output.NewFormatter(format, buf))
+}
+
+// decodeRows decodes the array of row objects of JSON output
+func decodeRows(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, data)
+	}
+	return rows
+}
+
+const ordersTable = `CREATE TABLE orders (
+	id INTEGER PRIMARY KEY,
+	customer VARCHAR(50) NOT NULL,
+	total DECIMAL(10,2) DEFAULT 0,
+	status TEXT DEFAULT 'new'
+)`
+
+func TestDescribe(t *testing.T) {
+	var buf bytes.Buffer
+	introspector := newTestIntrospector(t, "json", &buf, ordersTable)
+
+	if err := introspector.ProcessSchemaCommand("@describe", "ORDERS"); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@describe) returned error: %v", err)
+	}
+
+	columns := decodeRows(t, buf.Bytes())
+	if len(columns) != 4 {
+		t.Fatalf("Expected 4 columns, got %d:\n%s", len(columns), buf.String())
+	}
+
+	expected := []map[string]interface{}{
+		{"ordinal": 1.0, "column_name": "id", "type": "INTEGER", "nullable": true, "default": nil, "primary_key": true},
+		{"ordinal": 2.0, "column_name": "customer", "type": "VARCHAR(50)", "nullable": false, "length": 50.0},
+		{"ordinal": 3.0, "column_name": "total", "default": "0", "precision": 10.0, "scale": 2.0},
+		{"ordinal": 4.0, "column_name": "status", "default": "'new'", "primary_key": false, "length": nil},
+	}
+	for n, fields := range expected {
+		for key, value := range fields {
+			if columns[n][key] != value {
+				t.Errorf("Column %d: expected %s = %v, got %v", n+1, key, value, columns[n][key])
+			}
+		}
+	}
+}
+
+func TestDescribeErrors(t *testing.T) {
+	var buf bytes.Buffer
+	introspector := newTestIntrospector(t, "table", &buf, ordersTable)
+
+	if err := introspector.ProcessSchemaCommand("@describe", ""); err == nil {
+		t.Error("Expected an error without a table name")
+	}
+	if err := introspector.ProcessSchemaCommand("@describe", "missing"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestSchemaColumns(t *testing.T) {
+	var buf bytes.Buffer
+	introspector := newTestIntrospector(t, "csv", &buf, ordersTable,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE notes (body TEXT)")
+
+	if err := introspector.ProcessSchemaCommand("@schema-columns", "c"); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@schema-columns) returned error: %v", err)
+	}
+
+	expected := "table_name,ordinal,column_name,type,nullable,default,primary_key,length,precision,scale\n" +
+		"customers,1,id,INTEGER,true,,true,,,\n" +
+		"customers,2,name,TEXT,true,,false,,,\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestColumnCommands(t *testing.T) {
+	for _, line := range []string{"@schema-columns", "@describe orders"} {
+		if !IsSchemaCommand(line) {
+			t.Errorf("IsSchemaCommand(%s) = false, expected true", line)
+		}
+	}
+}
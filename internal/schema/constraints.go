@@ -0,0 +1,461 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gosqlpp/internal/output"
+
+	"github.com/jimsmart/schema"
+)
+
+// Constraint types, of which @schema-constraints reports all but CHECK
+const (
+	constraintPrimaryKey = "PRIMARY KEY"
+	constraintUnique     = "UNIQUE"
+	constraintForeignKey = "FOREIGN KEY"
+	constraintCheck      = "CHECK"
+)
+
+// indexInfo describes an index and its key columns in order
+type indexInfo struct {
+	table   string
+	name    string
+	columns []string
+	unique  bool
+	primary bool
+}
+
+// constraintInfo describes a primary key, unique or foreign key constraint.
+// The referenced table, columns and rules only apply to foreign keys, and
+// the definition to check constraints.
+type constraintInfo struct {
+	table      string
+	name       string
+	kind       string
+	columns    []string
+	refTable   string
+	refColumns []string
+	onDelete   string
+	onUpdate   string
+	definition string
+}
+
+// processSchemaIndexes processes @schema-indexes command
+func (i *Introspector) processSchemaIndexes(filter string) error {
+	indexes, err := i.getIndexes(filter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve indexes: %w", err)
+	}
+
+	if len(indexes) == 0 {
+		return i.formatter.WriteMessage("No indexes found")
+	}
+
+	var indexInfo []output.Record
+	for _, index := range indexes {
+		indexInfo = append(indexInfo, output.Record{
+			{Key: "table_name", Value: index.table},
+			{Key: "index_name", Value: index.name},
+			{Key: "columns", Value: strings.Join(index.columns, ", ")},
+			{Key: "unique", Value: index.unique},
+			{Key: "primary_key", Value: index.primary},
+		})
+	}
+
+	return i.formatSchemaResults(indexInfo)
+}
+
+// processSchemaConstraints processes @schema-constraints command
+func (i *Introspector) processSchemaConstraints(filter string) error {
+	constraints, err := i.getConstraints(filter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve constraints: %w", err)
+	}
+
+	if len(constraints) == 0 {
+		return i.formatter.WriteMessage("No constraints found")
+	}
+
+	var constraintInfo []output.Record
+	for _, constraint := range constraints {
+		constraintInfo = append(constraintInfo, output.Record{
+			{Key: "table_name", Value: constraint.table},
+			{Key: "constraint_name", Value: nullIfEmpty(constraint.name)},
+			{Key: "type", Value: constraint.kind},
+			{Key: "columns", Value: strings.Join(constraint.columns, ", ")},
+			{Key: "referenced_table", Value: nullIfEmpty(constraint.refTable)},
+			{Key: "referenced_columns", Value: nullIfEmpty(strings.Join(constraint.refColumns, ", "))},
+			{Key: "on_delete", Value: nullIfEmpty(constraint.onDelete)},
+			{Key: "on_update", Value: nullIfEmpty(constraint.onUpdate)},
+		})
+	}
+
+	return i.formatSchemaResults(constraintInfo)
+}
+
+// processSchemaForeignKeys processes @schema-foreignkeys command
+func (i *Introspector) processSchemaForeignKeys(filter string) error {
+	constraints, err := i.getConstraints(filter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve foreign keys: %w", err)
+	}
+
+	var foreignKeyInfo []output.Record
+	for _, constraint := range constraints {
+		if constraint.kind != constraintForeignKey {
+			continue
+		}
+		foreignKeyInfo = append(foreignKeyInfo, output.Record{
+			{Key: "table_name", Value: constraint.table},
+			{Key: "constraint_name", Value: nullIfEmpty(constraint.name)},
+			{Key: "columns", Value: strings.Join(constraint.columns, ", ")},
+			{Key: "referenced_table", Value: constraint.refTable},
+			{Key: "referenced_columns", Value: strings.Join(constraint.refColumns, ", ")},
+			{Key: "on_delete", Value: nullIfEmpty(constraint.onDelete)},
+			{Key: "on_update", Value: nullIfEmpty(constraint.onUpdate)},
+		})
+	}
+
+	if len(foreignKeyInfo) == 0 {
+		return i.formatter.WriteMessage("No foreign keys found")
+	}
+
+	return i.formatSchemaResults(foreignKeyInfo)
+}
+
+// nullIfEmpty returns nil for an empty string, so that it is shown as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// matchesFilter reports whether a name matches a prefix filter
+func matchesFilter(name, filter string) bool {
+	return filter == "" || len(filterNames([]string{name}, filter)) > 0
+}
+
+// getIndexes retrieves the indexes of the tables matching the filter
+// (database-specific)
+func (i *Introspector) getIndexes(filter string) ([]indexInfo, error) {
+	var query string
+
+	switch i.connection.Driver {
+	case "sqlite3":
+		return i.getSQLiteIndexes(filter)
+	case "postgres":
+		query = `SELECT t.relname, c.relname, ix.indisunique, ix.indisprimary, a.attname
+				FROM pg_index ix
+				JOIN pg_class t ON t.oid = ix.indrelid
+				JOIN pg_class c ON c.oid = ix.indexrelid
+				JOIN pg_namespace n ON n.oid = t.relnamespace
+				JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+				JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+				WHERE n.nspname = current_schema()
+				ORDER BY t.relname, c.relname, k.ord`
+	case "mysql":
+		query = `SELECT table_name, index_name, non_unique = 0, index_name = 'PRIMARY', column_name
+				FROM information_schema.statistics
+				WHERE table_schema = DATABASE() AND column_name IS NOT NULL
+				ORDER BY table_name, index_name, seq_in_index`
+	case "sqlserver":
+		query = `SELECT t.name, ix.name, ix.is_unique, ix.is_primary_key, c.name
+				FROM sys.indexes ix
+				JOIN sys.tables t ON t.object_id = ix.object_id
+				JOIN sys.index_columns ic ON ic.object_id = ix.object_id AND ic.index_id = ix.index_id
+				JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+				WHERE ix.name IS NOT NULL AND ic.is_included_column = 0 AND t.schema_id = SCHEMA_ID()
+				ORDER BY t.name, ix.name, ic.key_ordinal`
+	default:
+		return nil, fmt.Errorf("indexes not supported for driver: %s", i.connection.Driver)
+	}
+
+	rows, err := i.connection.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Each row holds one column of an index, in key order
+	var indexes []indexInfo
+	for rows.Next() {
+		var index indexInfo
+		var column string
+		if err := rows.Scan(&index.table, &index.name, &index.unique, &index.primary, &column); err != nil {
+			return nil, err
+		}
+		if !matchesFilter(index.table, filter) {
+			continue
+		}
+		if last := len(indexes) - 1; last >= 0 && indexes[last].table == index.table && indexes[last].name == index.name {
+			indexes[last].columns = append(indexes[last].columns, column)
+			continue
+		}
+		index.columns = []string{column}
+		indexes = append(indexes, index)
+	}
+
+	return indexes, rows.Err()
+}
+
+// getConstraints retrieves the primary key, unique and foreign key
+// constraints of the tables matching the filter (database-specific)
+func (i *Introspector) getConstraints(filter string) ([]constraintInfo, error) {
+	var query string
+
+	switch i.connection.Driver {
+	case "sqlite3":
+		return i.getSQLiteConstraints(filter)
+	case "postgres":
+		query = `SELECT t.relname, c.conname,
+					CASE c.contype WHEN 'p' THEN 'PRIMARY KEY' WHEN 'u' THEN 'UNIQUE' ELSE 'FOREIGN KEY' END,
+					a.attname, rt.relname, ra.attname,
+					CASE c.confdeltype WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+						WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' END,
+					CASE c.confupdtype WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+						WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' END
+				FROM pg_constraint c
+				JOIN pg_class t ON t.oid = c.conrelid
+				JOIN pg_namespace n ON n.oid = t.relnamespace
+				JOIN LATERAL unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord) ON true
+				JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+				LEFT JOIN pg_class rt ON rt.oid = c.confrelid
+				LEFT JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = c.confkey[k.ord]
+				WHERE n.nspname = current_schema() AND c.contype IN ('p', 'u', 'f')
+				ORDER BY t.relname, c.conname, k.ord`
+	case "mysql":
+		query = `SELECT k.table_name, k.constraint_name, c.constraint_type, k.column_name,
+					k.referenced_table_name, k.referenced_column_name, r.delete_rule, r.update_rule
+				FROM information_schema.key_column_usage k
+				JOIN information_schema.table_constraints c ON c.constraint_schema = k.constraint_schema
+					AND c.table_name = k.table_name AND c.constraint_name = k.constraint_name
+				LEFT JOIN information_schema.referential_constraints r ON r.constraint_schema = k.constraint_schema
+					AND r.table_name = k.table_name AND r.constraint_name = k.constraint_name
+				WHERE k.table_schema = DATABASE() AND c.constraint_type IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY')
+				ORDER BY k.table_name, k.constraint_name, k.ordinal_position`
+	case "sqlserver":
+		query = `SELECT table_name, constraint_name, constraint_type, column_name,
+					referenced_table, referenced_column, on_delete, on_update
+				FROM (
+					SELECT t.name AS table_name, kc.name AS constraint_name,
+						CASE kc.type WHEN 'PK' THEN 'PRIMARY KEY' ELSE 'UNIQUE' END AS constraint_type,
+						c.name AS column_name, NULL AS referenced_table, NULL AS referenced_column,
+						NULL AS on_delete, NULL AS on_update, ic.key_ordinal AS ordinal
+					FROM sys.key_constraints kc
+					JOIN sys.tables t ON t.object_id = kc.parent_object_id
+					JOIN sys.index_columns ic ON ic.object_id = kc.parent_object_id AND ic.index_id = kc.unique_index_id
+					JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+					WHERE t.schema_id = SCHEMA_ID()
+					UNION ALL
+					SELECT t.name, fk.name, 'FOREIGN KEY', c.name, rt.name, rc.name,
+						REPLACE(fk.delete_referential_action_desc, '_', ' '),
+						REPLACE(fk.update_referential_action_desc, '_', ' '), fc.constraint_column_id
+					FROM sys.foreign_keys fk
+					JOIN sys.tables t ON t.object_id = fk.parent_object_id
+					JOIN sys.foreign_key_columns fc ON fc.constraint_object_id = fk.object_id
+					JOIN sys.columns c ON c.object_id = fc.parent_object_id AND c.column_id = fc.parent_column_id
+					JOIN sys.tables rt ON rt.object_id = fk.referenced_object_id
+					JOIN sys.columns rc ON rc.object_id = fc.referenced_object_id AND rc.column_id = fc.referenced_column_id
+					WHERE t.schema_id = SCHEMA_ID()
+				) AS constraints
+				ORDER BY table_name, constraint_name, ordinal`
+	default:
+		return nil, fmt.Errorf("constraints not supported for driver: %s", i.connection.Driver)
+	}
+
+	rows, err := i.connection.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Each row holds one column of a constraint, in key order
+	var constraints []constraintInfo
+	for rows.Next() {
+		var constraint constraintInfo
+		var column string
+		var refTable, refColumn, onDelete, onUpdate sql.NullString
+		if err := rows.Scan(&constraint.table, &constraint.name, &constraint.kind, &column,
+			&refTable, &refColumn, &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+		if !matchesFilter(constraint.table, filter) {
+			continue
+		}
+		last := len(constraints) - 1
+		if last < 0 || constraints[last].table != constraint.table || constraints[last].name != constraint.name {
+			constraint.refTable = refTable.String
+			constraint.onDelete = onDelete.String
+			constraint.onUpdate = onUpdate.String
+			constraints = append(constraints, constraint)
+			last++
+		}
+		constraints[last].columns = append(constraints[last].columns, column)
+		if refColumn.Valid {
+			constraints[last].refColumns = append(constraints[last].refColumns, refColumn.String)
+		}
+	}
+
+	return constraints, rows.Err()
+}
+
+// getSQLiteIndexes retrieves indexes with PRAGMA index_list and index_info.
+// A rowid alias, declared as INTEGER PRIMARY KEY, has no index.
+func (i *Introspector) getSQLiteIndexes(filter string) ([]indexInfo, error) {
+	tables, err := i.tableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []indexInfo
+	for _, table := range filterNames(tables, filter) {
+		tableIndexes, _, err := i.sqliteIndexes(table)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, tableIndexes...)
+	}
+
+	return indexes, nil
+}
+
+// sqliteIndexes lists the indexes of a table with their columns, along
+// with the origin of each: CREATE INDEX (c), a UNIQUE constraint (u) or a
+// PRIMARY KEY (pk)
+func (i *Introspector) sqliteIndexes(table string) ([]indexInfo, []string, error) {
+	rows, err := i.connection.DB.Query(`SELECT name, "unique", origin FROM pragma_index_list(?) ORDER BY name`, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var indexes []indexInfo
+	var origins []string
+	for rows.Next() {
+		index := indexInfo{table: table}
+		var origin string
+		if err := rows.Scan(&index.name, &index.unique, &origin); err != nil {
+			return nil, nil, err
+		}
+		index.primary = origin == "pk"
+		indexes = append(indexes, index)
+		origins = append(origins, origin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	// The columns are read once the list is closed, as an in-memory
+	// database may only have a single connection
+	for n := range indexes {
+		columns, err := i.executeStringQuery(`SELECT name FROM pragma_index_info(?) ORDER BY seqno`, indexes[n].name)
+		if err != nil {
+			return nil, nil, err
+		}
+		indexes[n].columns = columns
+	}
+
+	return indexes, origins, nil
+}
+
+// getSQLiteConstraints builds constraints from the primary key columns of
+// PRAGMA table_info, the UNIQUE constraint indexes of PRAGMA index_list and
+// PRAGMA foreign_key_list. SQLite does not keep constraint names.
+func (i *Introspector) getSQLiteConstraints(filter string) ([]constraintInfo, error) {
+	tables, err := i.tableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var constraints []constraintInfo
+	for _, table := range filterNames(tables, filter) {
+		primaryKey, err := schema.PrimaryKey(i.connection.DB, "", table)
+		if err != nil {
+			return nil, err
+		}
+		if len(primaryKey) > 0 {
+			constraints = append(constraints, constraintInfo{table: table, kind: constraintPrimaryKey, columns: primaryKey})
+		}
+
+		indexes, origins, err := i.sqliteIndexes(table)
+		if err != nil {
+			return nil, err
+		}
+		for n, index := range indexes {
+			if origins[n] == "u" {
+				constraints = append(constraints, constraintInfo{table: table, kind: constraintUnique, columns: index.columns})
+			}
+		}
+
+		foreignKeys, err := i.sqliteForeignKeys(table)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, foreignKeys...)
+	}
+
+	return constraints, nil
+}
+
+// sqliteForeignKeys lists the foreign keys of a table with PRAGMA
+// foreign_key_list. A foreign key that omits the referenced columns refers
+// to the primary key of the referenced table.
+func (i *Introspector) sqliteForeignKeys(table string) ([]constraintInfo, error) {
+	rows, err := i.connection.DB.Query(`SELECT id, "table", "from", "to", on_delete, on_update
+		FROM pragma_foreign_key_list(?) ORDER BY id, seq`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []constraintInfo
+	var ids []int
+	var implicit []bool
+	for rows.Next() {
+		var id int
+		var refTable, column, onDelete, onUpdate string
+		var refColumn sql.NullString
+		if err := rows.Scan(&id, &refTable, &column, &refColumn, &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+		if last := len(ids) - 1; last < 0 || ids[last] != id {
+			foreignKeys = append(foreignKeys, constraintInfo{
+				table:    table,
+				kind:     constraintForeignKey,
+				refTable: refTable,
+				onDelete: onDelete,
+				onUpdate: onUpdate,
+			})
+			ids = append(ids, id)
+			implicit = append(implicit, false)
+		}
+		last := len(foreignKeys) - 1
+		foreignKeys[last].columns = append(foreignKeys[last].columns, column)
+		if refColumn.Valid {
+			foreignKeys[last].refColumns = append(foreignKeys[last].refColumns, refColumn.String)
+		} else {
+			implicit[last] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for n := range foreignKeys {
+		if !implicit[n] {
+			continue
+		}
+		refColumns, err := schema.PrimaryKey(i.connection.DB, "", foreignKeys[n].refTable)
+		if err != nil {
+			return nil, err
+		}
+		foreignKeys[n].refColumns = refColumns
+	}
+
+	return foreignKeys, nil
+}
@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// constraintTables creates customers and orders tables, with a composite
+// foreign key from order_lines to orders and an implicit one to products
+var constraintTables = []string{
+	"CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT UNIQUE)",
+	`CREATE TABLE orders (
+		customer_id INTEGER REFERENCES customers (id) ON DELETE CASCADE,
+		number INTEGER,
+		PRIMARY KEY (customer_id, number)
+	)`,
+	"CREATE INDEX orders_by_number ON orders (number, customer_id)",
+	"CREATE TABLE products (code TEXT PRIMARY KEY)",
+	`CREATE TABLE order_lines (
+		customer_id INTEGER,
+		number INTEGER,
+		product TEXT REFERENCES products ON UPDATE SET NULL,
+		FOREIGN KEY (customer_id, number) REFERENCES orders (customer_id, number)
+	)`,
+}
+
+// schemaRecords runs a schema command and decodes its JSON output
+func schemaRecords(t *testing.T, command, filter string) []map[string]interface{} {
+	t.Helper()
+
+	var buf bytes.Buffer
+	introspector := newTestIntrospector(t, "json", &buf, constraintTables...)
+	if err := introspector.ProcessSchemaCommand(command, filter); err != nil {
+		t.Fatalf("ProcessSchemaCommand(%s) returned error: %v", command, err)
+	}
+
+	return decodeRows(t, buf.Bytes())
+}
+
+// checkRecords compares the given fields of each record
+func checkRecords(t *testing.T, records []map[string]interface{}, expected []map[string]interface{}) {
+	t.Helper()
+
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d: %v", len(expected), len(records), records)
+	}
+	for n, fields := range expected {
+		for key, value := range fields {
+			if records[n][key] != value {
+				t.Errorf("Record %d: expected %s = %v, got %v", n+1, key, value, records[n][key])
+			}
+		}
+	}
+}
+
+func TestSchemaIndexes(t *testing.T) {
+	checkRecords(t, schemaRecords(t, "@schema-indexes", "ord"), []map[string]interface{}{
+		{"table_name": "orders", "index_name": "orders_by_number", "columns": "number, customer_id", "unique": false, "primary_key": false},
+		{"table_name": "orders", "index_name": "sqlite_autoindex_orders_1", "columns": "customer_id, number", "unique": true, "primary_key": true},
+	})
+}
+
+func TestSchemaConstraints(t *testing.T) {
+	checkRecords(t, schemaRecords(t, "@schema-constraints", "c"), []map[string]interface{}{
+		{"table_name": "customers", "constraint_name": nil, "type": "PRIMARY KEY", "columns": "id", "referenced_table": nil},
+		{"table_name": "customers", "type": "UNIQUE", "columns": "email"},
+	})
+
+	checkRecords(t, schemaRecords(t, "@schema-constraints", "orders"), []map[string]interface{}{
+		{"type": "PRIMARY KEY", "columns": "customer_id, number"},
+		{"type": "FOREIGN KEY", "columns": "customer_id", "referenced_table": "customers",
+			"referenced_columns": "id", "on_delete": "CASCADE", "on_update": "NO ACTION"},
+	})
+}
+
+func TestSchemaForeignKeys(t *testing.T) {
+	checkRecords(t, schemaRecords(t, "@schema-foreignkeys", ""), []map[string]interface{}{
+		{"table_name": "order_lines", "columns": "customer_id, number", "referenced_table": "orders",
+			"referenced_columns": "customer_id, number"},
+		{"table_name": "order_lines", "columns": "product", "referenced_table": "products",
+			"referenced_columns": "code", "on_update": "SET NULL"},
+		{"table_name": "orders", "columns": "customer_id", "referenced_table": "customers"},
+	})
+
+	var buf bytes.Buffer
+	introspector := newTestIntrospector(t, "table", &buf, "CREATE TABLE notes (body TEXT)")
+	if err := introspector.ProcessSchemaCommand("@schema-foreignkeys", ""); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@schema-foreignkeys) returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No foreign keys found") {
+		t.Errorf("Expected no foreign keys, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,702 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ddlObject is a table, view or routine with the statements that create it
+type ddlObject struct {
+	name       string
+	statements []string
+}
+
+// ddlColumn is a column of a table as declared in CREATE TABLE. For
+// PostgreSQL, identity is ALWAYS or BY DEFAULT for identity columns, and
+// serial marks integer columns that take their default from a sequence they
+// own, which are scripted as serial columns.
+type ddlColumn struct {
+	name         string
+	typeName     string
+	notNull      bool
+	defaultValue sql.NullString
+	identity     string
+	serial       bool
+}
+
+// serialTypes are the serial types of the PostgreSQL integer types
+var serialTypes = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"bigint":   "bigserial",
+}
+
+// processSchemaDDL processes @schema-ddl command, writing a script that
+// creates the tables, views and routines matching the filter. Sequences that
+// the tables use come first, tables before the tables and views that refer
+// to them, and routines last. Each statement is followed by a go line so
+// that the script can be run again with sqlpp.
+func (i *Introspector) processSchemaDDL(filter string) error {
+	tables, err := i.tableDDL(filter)
+	if err != nil {
+		return fmt.Errorf("failed to script tables: %w", err)
+	}
+	sequences, err := i.sequenceDDL(filter, tables)
+	if err != nil {
+		return fmt.Errorf("failed to script sequences: %w", err)
+	}
+	views, err := i.viewDDL(filter)
+	if err != nil {
+		return fmt.Errorf("failed to script views: %w", err)
+	}
+	routines, err := i.routineDDL(filter)
+	if err != nil {
+		return fmt.Errorf("failed to script routines: %w", err)
+	}
+
+	objects := append(append(append(sequences, tables...), views...), routines...)
+	if len(objects) == 0 {
+		return i.formatter.WriteMessage("No objects found")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema of %s (%s)\n", i.connection.Name, i.connection.Driver)
+	for _, object := range objects {
+		b.WriteString("\n")
+		for _, stmt := range object.statements {
+			fmt.Fprintf(&b, "%s\ngo\n", strings.TrimSpace(stmt))
+		}
+	}
+
+	return i.formatter.WriteSQL(b.String())
+}
+
+// tableDDL scripts the tables matching the filter with their keys,
+// constraints and indexes, ordered by their foreign keys
+func (i *Introspector) tableDDL(filter string) ([]ddlObject, error) {
+	constraints, err := i.getConstraints("")
+	if err != nil {
+		return nil, err
+	}
+	constraintsByTable := make(map[string][]constraintInfo)
+	for _, constraint := range constraints {
+		constraintsByTable[constraint.table] = append(constraintsByTable[constraint.table], constraint)
+	}
+
+	var objects []ddlObject
+	switch i.connection.Driver {
+	case "sqlite3":
+		objects, err = i.sqliteTableDDL()
+	case "mysql":
+		objects, err = i.mysqlTableDDL()
+	case "postgres", "sqlserver":
+		objects, err = i.buildTableDDL(constraintsByTable)
+	default:
+		return nil, fmt.Errorf("DDL not supported for driver: %s", i.connection.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dependencyOrder(filterObjects(objects, filter), func(object ddlObject) []string {
+		var refTables []string
+		for _, constraint := range constraintsByTable[object.name] {
+			if constraint.kind == constraintForeignKey {
+				refTables = append(refTables, constraint.refTable)
+			}
+		}
+		return refTables
+	}), nil
+}
+
+// sequenceDDL scripts the PostgreSQL sequences that match the filter or
+// that the scripted tables refer to. Sequences owned by identity and serial
+// columns are left out, as those columns create them.
+func (i *Introspector) sequenceDDL(filter string, tables []ddlObject) ([]ddlObject, error) {
+	if i.connection.Driver != "postgres" {
+		return nil, nil
+	}
+
+	objects, err := i.definitionObjects(`SELECT c.relname, 'CREATE SEQUENCE ' || quote_ident(c.relname)
+				|| ' AS ' || format_type(s.seqtypid, NULL)
+				|| ' INCREMENT BY ' || s.seqincrement || ' MINVALUE ' || s.seqmin || ' MAXVALUE ' || s.seqmax
+				|| ' START WITH ' || s.seqstart || CASE WHEN s.seqcycle THEN ' CYCLE' ELSE ' NO CYCLE' END
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_sequence s ON s.seqrelid = c.oid
+			WHERE c.relkind = 'S' AND n.nspname = current_schema() AND NOT EXISTS (
+				SELECT 1 FROM pg_depend dep
+				JOIN pg_attribute a ON a.attrelid = dep.refobjid AND a.attnum = dep.refobjsubid
+				LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+				WHERE dep.classid = 'pg_class'::regclass AND dep.objid = c.oid AND (dep.deptype = 'i'
+					OR dep.deptype = 'a' AND a.atttypid IN ('int2'::regtype, 'int4'::regtype, 'int8'::regtype)
+						AND pg_get_expr(d.adbin, d.adrelid) = 'nextval(' || quote_literal(c.oid::regclass::text) || '::regclass)'))
+			ORDER BY c.relname`)
+	if err != nil {
+		return nil, err
+	}
+
+	var sequences []ddlObject
+	for _, sequence := range objects {
+		used := matchesFilter(sequence.name, filter)
+		for _, table := range tables {
+			used = used || referencesName(strings.Join(table.statements, "\n"), sequence.name)
+		}
+		if used {
+			sequences = append(sequences, sequence)
+		}
+	}
+	return sequences, nil
+}
+
+// viewDDL scripts the views matching the filter, ordered so that views
+// come after the views they select from
+func (i *Introspector) viewDDL(filter string) ([]ddlObject, error) {
+	var objects []ddlObject
+	var err error
+
+	switch i.connection.Driver {
+	case "sqlite3":
+		objects, err = i.definitionObjects(`SELECT name, sql FROM sqlite_master WHERE type = 'view' ORDER BY rowid`)
+	case "postgres":
+		objects, err = i.definitionObjects(`SELECT c.relname,
+					CASE c.relkind WHEN 'm' THEN 'CREATE MATERIALIZED VIEW ' ELSE 'CREATE VIEW ' END
+					|| quote_ident(c.relname) || ' AS' || chr(10) || rtrim(pg_get_viewdef(c.oid, true), ';')
+				FROM pg_class c
+				JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE c.relkind IN ('v', 'm') AND n.nspname = current_schema()
+				ORDER BY c.oid`)
+	case "mysql":
+		objects, err = i.showCreateObjects(`SELECT table_name, 'VIEW' FROM information_schema.views
+				WHERE table_schema = DATABASE() ORDER BY table_name`)
+	case "sqlserver":
+		objects, err = i.definitionObjects(`SELECT name, OBJECT_DEFINITION(object_id) FROM sys.views
+				WHERE schema_id = SCHEMA_ID() ORDER BY object_id`)
+	default:
+		return nil, fmt.Errorf("DDL not supported for driver: %s", i.connection.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	views := filterObjects(objects, filter)
+	return dependencyOrder(views, func(view ddlObject) []string {
+		var names []string
+		for _, other := range views {
+			if other.name != view.name && referencesName(view.statements[0], other.name) {
+				names = append(names, other.name)
+			}
+		}
+		return names
+	}), nil
+}
+
+// routineDDL scripts the functions and procedures matching the filter,
+// functions first since procedures may call them. SQLite has no routines,
+// so its triggers are scripted instead, following the filter on their
+// table.
+func (i *Introspector) routineDDL(filter string) ([]ddlObject, error) {
+	var objects []ddlObject
+	var err error
+
+	switch i.connection.Driver {
+	case "sqlite3":
+		objects, err = i.definitionObjects(`SELECT tbl_name, sql FROM sqlite_master WHERE type = 'trigger' ORDER BY rowid`)
+	case "postgres":
+		objects, err = i.definitionObjects(`SELECT p.proname, pg_get_functiondef(p.oid)
+				FROM pg_proc p
+				JOIN pg_namespace n ON n.oid = p.pronamespace
+				WHERE n.nspname = current_schema() AND p.prokind IN ('f', 'p')
+					AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+				ORDER BY p.prokind, p.proname, p.oid`)
+	case "mysql":
+		objects, err = i.showCreateObjects(`SELECT routine_name, routine_type FROM information_schema.routines
+				WHERE routine_schema = DATABASE() ORDER BY routine_type, routine_name`)
+	case "sqlserver":
+		objects, err = i.definitionObjects(`SELECT name, OBJECT_DEFINITION(object_id) FROM sys.objects
+				WHERE type IN ('FN', 'IF', 'TF', 'P') AND schema_id = SCHEMA_ID()
+				ORDER BY CASE type WHEN 'P' THEN 1 ELSE 0 END, name`)
+	default:
+		return nil, fmt.Errorf("DDL not supported for driver: %s", i.connection.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return filterObjects(objects, filter), nil
+}
+
+// sqliteTableDDL scripts tables and their indexes from sqlite_master,
+// which keeps the statements that created them
+func (i *Introspector) sqliteTableDDL() ([]ddlObject, error) {
+	rows, err := i.connection.DB.Query(`SELECT type, name, tbl_name, sql FROM sqlite_master
+		WHERE type IN ('table', 'index') AND sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY CASE type WHEN 'table' THEN 0 ELSE 1 END, rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []ddlObject
+	tableIndex := make(map[string]int)
+	for rows.Next() {
+		var objectType, name, table, definition string
+		if err := rows.Scan(&objectType, &name, &table, &definition); err != nil {
+			return nil, err
+		}
+		if objectType == "table" {
+			tableIndex[name] = len(objects)
+			objects = append(objects, ddlObject{name: name, statements: []string{definition}})
+		} else if n, ok := tableIndex[table]; ok {
+			objects[n].statements = append(objects[n].statements, definition)
+		}
+	}
+
+	return objects, rows.Err()
+}
+
+// mysqlTableDDL scripts tables with SHOW CREATE TABLE, which includes
+// their keys, constraints and indexes
+func (i *Introspector) mysqlTableDDL() ([]ddlObject, error) {
+	tables, err := i.tableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []ddlObject
+	for _, table := range tables {
+		definition, err := i.showCreate("SHOW CREATE TABLE "+i.quoteIdent(table), "Create Table")
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, ddlObject{name: table, statements: []string{definition}})
+	}
+
+	return objects, nil
+}
+
+// buildTableDDL builds CREATE TABLE statements from the catalog, for
+// databases that cannot script a table themselves, followed by the indexes
+// that do not back a constraint
+func (i *Introspector) buildTableDDL(constraintsByTable map[string][]constraintInfo) ([]ddlObject, error) {
+	tables, err := i.tableNames()
+	if err != nil {
+		return nil, err
+	}
+
+	checks, err := i.getCheckConstraints()
+	if err != nil {
+		return nil, err
+	}
+	for _, check := range checks {
+		constraintsByTable[check.table] = append(constraintsByTable[check.table], check)
+	}
+
+	indexes, err := i.indexDefinitions(constraintsByTable)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []ddlObject
+	for _, table := range tables {
+		columns, err := i.ddlColumns(table)
+		if err != nil {
+			return nil, err
+		}
+		statements := []string{i.createTable(table, columns, constraintsByTable[table])}
+		objects = append(objects, ddlObject{name: table, statements: append(statements, indexes[table]...)})
+	}
+
+	return objects, nil
+}
+
+// createTable builds a CREATE TABLE statement with the columns followed by
+// the primary key, unique, foreign key and check constraints
+func (i *Introspector) createTable(table string, columns []ddlColumn, constraints []constraintInfo) string {
+	var lines []string
+	for _, column := range columns {
+		typeName, defaultValue := column.typeName, column.defaultValue
+		if serialType, ok := serialTypes[typeName]; ok && column.serial {
+			// The serial type creates the sequence and the default
+			typeName, defaultValue = serialType, sql.NullString{}
+		}
+
+		line := i.quoteIdent(column.name) + " " + typeName
+		if column.identity != "" {
+			line += " GENERATED " + column.identity + " AS IDENTITY"
+		}
+		if column.notNull {
+			line += " NOT NULL"
+		}
+		if defaultValue.Valid {
+			line += " DEFAULT " + defaultValue.String
+		}
+		lines = append(lines, line)
+	}
+
+	rank := map[string]int{constraintPrimaryKey: 0, constraintUnique: 1, constraintForeignKey: 2, constraintCheck: 3}
+	sort.SliceStable(constraints, func(a, b int) bool {
+		return rank[constraints[a].kind] < rank[constraints[b].kind]
+	})
+	for _, constraint := range constraints {
+		line := "CONSTRAINT " + i.quoteIdent(constraint.name) + " "
+		switch constraint.kind {
+		case constraintCheck:
+			line += constraint.definition
+		case constraintForeignKey:
+			line += fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", i.quoteIdents(constraint.columns),
+				i.quoteIdent(constraint.refTable), i.quoteIdents(constraint.refColumns))
+			if constraint.onDelete != "" && constraint.onDelete != "NO ACTION" {
+				line += " ON DELETE " + constraint.onDelete
+			}
+			if constraint.onUpdate != "" && constraint.onUpdate != "NO ACTION" {
+				line += " ON UPDATE " + constraint.onUpdate
+			}
+		default:
+			line += fmt.Sprintf("%s (%s)", constraint.kind, i.quoteIdents(constraint.columns))
+		}
+		lines = append(lines, line)
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", i.quoteIdent(table), strings.Join(lines, ",\n    "))
+}
+
+// ddlColumns retrieves the columns of a table with their declared types,
+// and for PostgreSQL their identity and whether they are serial columns
+// (database-specific)
+func (i *Introspector) ddlColumns(table string) ([]ddlColumn, error) {
+	var query string
+
+	switch i.connection.Driver {
+	case "postgres":
+		query = `SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull, pg_get_expr(d.adbin, d.adrelid),
+					CASE a.attidentity WHEN 'a' THEN 'ALWAYS' WHEN 'd' THEN 'BY DEFAULT' END,
+					EXISTS (SELECT 1 FROM pg_depend dep
+						WHERE dep.classid = 'pg_class'::regclass AND dep.refobjid = a.attrelid
+							AND dep.refobjsubid = a.attnum AND dep.deptype = 'a'
+							AND pg_get_expr(d.adbin, d.adrelid) = 'nextval(' || quote_literal(dep.objid::regclass::text) || '::regclass)')
+				FROM pg_attribute a
+				JOIN pg_class t ON t.oid = a.attrelid
+				JOIN pg_namespace n ON n.oid = t.relnamespace
+				LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+				WHERE n.nspname = current_schema() AND t.relname = $1 AND a.attnum > 0 AND NOT a.attisdropped
+				ORDER BY a.attnum`
+	case "sqlserver":
+		query = `SELECT c.name,
+					CASE
+						WHEN ty.name IN ('varchar', 'char', 'varbinary', 'binary') THEN ty.name + '('
+							+ CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length AS varchar(10)) END + ')'
+						WHEN ty.name IN ('nvarchar', 'nchar') THEN ty.name + '('
+							+ CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length / 2 AS varchar(10)) END + ')'
+						WHEN ty.name IN ('decimal', 'numeric') THEN ty.name + '('
+							+ CAST(c.precision AS varchar(10)) + ',' + CAST(c.scale AS varchar(10)) + ')'
+						WHEN ty.name IN ('datetime2', 'datetimeoffset', 'time') THEN ty.name + '('
+							+ CAST(c.scale AS varchar(10)) + ')'
+						ELSE ty.name
+					END
+					+ CASE WHEN ic.object_id IS NULL THEN '' ELSE ' IDENTITY('
+						+ CAST(ic.seed_value AS varchar(40)) + ',' + CAST(ic.increment_value AS varchar(40)) + ')' END,
+					CAST(1 - c.is_nullable AS bit),
+					dc.definition,
+					NULL,
+					CAST(0 AS bit)
+				FROM sys.columns c
+				JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+				LEFT JOIN sys.identity_columns ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+				LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+				WHERE c.object_id = OBJECT_ID(QUOTENAME(SCHEMA_NAME()) + '.' + QUOTENAME(@p1))
+				ORDER BY c.column_id`
+	default:
+		return nil, fmt.Errorf("DDL not supported for driver: %s", i.connection.Driver)
+	}
+
+	rows, err := i.connection.DB.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []ddlColumn
+	for rows.Next() {
+		var column ddlColumn
+		var identity sql.NullString
+		if err := rows.Scan(&column.name, &column.typeName, &column.notNull, &column.defaultValue,
+			&identity, &column.serial); err != nil {
+			return nil, err
+		}
+		column.identity = identity.String
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// getCheckConstraints retrieves check constraints with their definitions
+// (database-specific)
+func (i *Introspector) getCheckConstraints() ([]constraintInfo, error) {
+	var query string
+
+	switch i.connection.Driver {
+	case "postgres":
+		query = `SELECT t.relname, c.conname, pg_get_constraintdef(c.oid)
+				FROM pg_constraint c
+				JOIN pg_class t ON t.oid = c.conrelid
+				JOIN pg_namespace n ON n.oid = t.relnamespace
+				WHERE n.nspname = current_schema() AND c.contype = 'c'
+				ORDER BY t.relname, c.conname`
+	case "sqlserver":
+		query = `SELECT t.name, cc.name, 'CHECK ' + cc.definition
+				FROM sys.check_constraints cc
+				JOIN sys.tables t ON t.object_id = cc.parent_object_id
+				WHERE t.schema_id = SCHEMA_ID()
+				ORDER BY t.name, cc.name`
+	default:
+		return nil, fmt.Errorf("check constraints not supported for driver: %s", i.connection.Driver)
+	}
+
+	rows, err := i.connection.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []constraintInfo
+	for rows.Next() {
+		check := constraintInfo{kind: constraintCheck}
+		if err := rows.Scan(&check.table, &check.name, &check.definition); err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, rows.Err()
+}
+
+// indexDefinitions returns CREATE INDEX statements keyed by table for the
+// indexes that do not back a primary key or unique constraint
+// (database-specific)
+func (i *Introspector) indexDefinitions(constraintsByTable map[string][]constraintInfo) (map[string][]string, error) {
+	definitions := make(map[string][]string)
+
+	if i.connection.Driver == "postgres" {
+		rows, err := i.connection.DB.Query(`SELECT t.relname, pg_get_indexdef(ix.indexrelid)
+			FROM pg_index ix
+			JOIN pg_class t ON t.oid = ix.indrelid
+			JOIN pg_class c ON c.oid = ix.indexrelid
+			JOIN pg_namespace n ON n.oid = t.relnamespace
+			WHERE n.nspname = current_schema() AND NOT EXISTS (
+				SELECT 1 FROM pg_constraint k
+				WHERE k.conindid = ix.indexrelid AND k.conrelid = ix.indrelid AND k.contype IN ('p', 'u', 'x'))
+			ORDER BY t.relname, c.relname`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var table, definition string
+			if err := rows.Scan(&table, &definition); err != nil {
+				return nil, err
+			}
+			definitions[table] = append(definitions[table], definition)
+		}
+		return definitions, rows.Err()
+	}
+
+	indexes, err := i.getIndexes("")
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		backsConstraint := false
+		for _, constraint := range constraintsByTable[index.table] {
+			backsConstraint = backsConstraint || (constraint.name == index.name && constraint.kind != constraintForeignKey)
+		}
+		if backsConstraint {
+			continue
+		}
+		create := "CREATE INDEX"
+		if index.unique {
+			create = "CREATE UNIQUE INDEX"
+		}
+		definitions[index.table] = append(definitions[index.table], fmt.Sprintf("%s %s ON %s (%s)",
+			create, i.quoteIdent(index.name), i.quoteIdent(index.table), i.quoteIdents(index.columns)))
+	}
+	return definitions, nil
+}
+
+// definitionObjects runs a query returning the name and definition of
+// objects. A definition the database does not show, such as that of an
+// encrypted routine, is replaced by a comment.
+func (i *Introspector) definitionObjects(query string) ([]ddlObject, error) {
+	rows, err := i.connection.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []ddlObject
+	for rows.Next() {
+		var name string
+		var definition sql.NullString
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, err
+		}
+		if !definition.Valid {
+			definition.String = fmt.Sprintf("-- The definition of %s is not available", name)
+		}
+		objects = append(objects, ddlObject{name: name, statements: []string{definition.String}})
+	}
+
+	return objects, rows.Err()
+}
+
+// showCreateObjects runs a query returning the name and type of MySQL
+// objects and scripts each with SHOW CREATE
+func (i *Introspector) showCreateObjects(query string) ([]ddlObject, error) {
+	rows, err := i.connection.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names, objectTypes []string
+	for rows.Next() {
+		var name, objectType string
+		if err := rows.Scan(&name, &objectType); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		objectTypes = append(objectTypes, objectType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	var objects []ddlObject
+	for n, name := range names {
+		objectType := strings.ToUpper(objectTypes[n])
+		column := "Create " + objectType[:1] + strings.ToLower(objectType[1:])
+		definition, err := i.showCreate(fmt.Sprintf("SHOW CREATE %s %s", objectType, i.quoteIdent(name)), column)
+		if err != nil {
+			return nil, err
+		}
+		if definition == "" {
+			definition = fmt.Sprintf("-- The definition of %s is not available", name)
+		}
+		objects = append(objects, ddlObject{name: name, statements: []string{definition}})
+	}
+
+	return objects, nil
+}
+
+// showCreate runs a MySQL SHOW CREATE statement and returns the value of
+// the named column
+func (i *Introspector) showCreate(query, column string) (string, error) {
+	rows, err := i.connection.DB.Query(query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]sql.NullString, len(columns))
+	targets := make([]interface{}, len(columns))
+	for n := range values {
+		targets[n] = &values[n]
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s returned no rows", query)
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return "", err
+	}
+	for n, name := range columns {
+		if strings.EqualFold(name, column) {
+			return values[n].String, nil
+		}
+	}
+	return "", fmt.Errorf("%s returned no %s column", query, column)
+}
+
+// quoteIdent quotes an identifier for the connection's driver
+func (i *Introspector) quoteIdent(name string) string {
+	switch i.connection.Driver {
+	case "mysql":
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case "sqlserver":
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// quoteIdents quotes identifiers and joins them with commas
+func (i *Introspector) quoteIdents(names []string) string {
+	quoted := make([]string, len(names))
+	for n, name := range names {
+		quoted[n] = i.quoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// filterObjects keeps the objects whose name matches a prefix filter
+func filterObjects(objects []ddlObject, filter string) []ddlObject {
+	var filtered []ddlObject
+	for _, object := range objects {
+		if matchesFilter(object.name, filter) {
+			filtered = append(filtered, object)
+		}
+	}
+	return filtered
+}
+
+// referencesName reports whether a definition mentions a name as a whole
+// identifier, ignoring case
+func referencesName(definition, name string) bool {
+	pattern := `(?i)(^|[^\w$])` + regexp.QuoteMeta(name) + `($|[^\w$])`
+	return regexp.MustCompile(pattern).MatchString(definition)
+}
+
+// dependencyOrder orders objects so that each comes after the objects it
+// depends on, and otherwise keeps their order. Objects in a dependency
+// cycle are left in their order.
+func dependencyOrder(objects []ddlObject, dependsOn func(ddlObject) []string) []ddlObject {
+	byName := make(map[string]int, len(objects))
+	for n, object := range objects {
+		byName[strings.ToLower(object.name)] = n
+	}
+
+	ordered := make([]ddlObject, 0, len(objects))
+	state := make([]int, len(objects)) // 0 unvisited, 1 visiting, 2 done
+	var visit func(n int)
+	visit = func(n int) {
+		if state[n] != 0 {
+			return
+		}
+		state[n] = 1
+		for _, name := range dependsOn(objects[n]) {
+			if dependency, ok := byName[strings.ToLower(name)]; ok {
+				visit(dependency)
+			}
+		}
+		state[n] = 2
+		ordered = append(ordered, objects[n])
+	}
+
+	for n := range objects {
+		visit(n)
+	}
+	return ordered
+}
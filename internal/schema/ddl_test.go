@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"gosqlpp/internal/database"
+)
+
+func TestSchemaDDL(t *testing.T) {
+	var buf bytes.Buffer
+	setup := []string{
+		// Created before the tables it refers to, to check the ordering
+		"CREATE TABLE order_lines (order_id INTEGER REFERENCES orders (id), product TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer TEXT NOT NULL)",
+		"CREATE INDEX orders_by_customer ON orders (customer)",
+		"CREATE VIEW big_orders AS SELECT * FROM order_totals WHERE lines > 10",
+		"CREATE VIEW order_totals AS SELECT order_id, COUNT(*) AS lines FROM order_lines GROUP BY order_id",
+		"CREATE TRIGGER orders_delete AFTER DELETE ON orders BEGIN DELETE FROM order_lines WHERE order_id = old.id; END",
+	}
+	introspector := newTestIntrospector(t, "table", &buf, setup...)
+
+	if err := introspector.ProcessSchemaCommand("@schema-ddl", ""); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@schema-ddl) returned error: %v", err)
+	}
+	script := buf.String()
+
+	expected := []string{setup[1], setup[2], setup[0], setup[4], setup[3], setup[5]}
+	last := -1
+	for _, stmt := range expected {
+		position := strings.Index(script, stmt+"\ngo\n")
+		if position < 0 {
+			t.Fatalf("Expected the script to contain %q, got:\n%s", stmt, script)
+		}
+		if position < last {
+			t.Errorf("Expected %q to come later, got:\n%s", stmt, script)
+		}
+		last = position
+	}
+
+	// The script recreates the schema
+	var copyBuf bytes.Buffer
+	copyIntrospector := newTestIntrospector(t, "table", &copyBuf)
+	for _, stmt := range strings.Split(script, "\ngo\n") {
+		if _, err := copyIntrospector.connection.DB.Exec(stmt); err != nil {
+			t.Fatalf("Failed to run %q: %v", stmt, err)
+		}
+	}
+	if err := copyIntrospector.ProcessSchemaCommand("@schema-ddl", ""); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@schema-ddl) returned error: %v", err)
+	}
+	if copyBuf.String() != script {
+		t.Errorf("Expected the same script for the copy, got:\n%s\nexpected:\n%s", copyBuf.String(), script)
+	}
+}
+
+func TestSchemaDDLFilter(t *testing.T) {
+	var buf bytes.Buffer
+	introspector := newTestIntrospector(t, "table", &buf,
+		"CREATE TABLE orders (id INTEGER)", "CREATE TABLE customers (id INTEGER)")
+
+	if err := introspector.ProcessSchemaCommand("@schema-ddl", "cust"); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@schema-ddl) returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "CREATE TABLE customers") || strings.Contains(buf.String(), "orders") {
+		t.Errorf("Expected only the customers table, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := introspector.ProcessSchemaCommand("@schema-ddl", "x"); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@schema-ddl) returned error: %v", err)
+	}
+	if buf.String() != "No objects found\n" {
+		t.Errorf("Expected no objects, got:\n%s", buf.String())
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	introspector := &Introspector{connection: &database.Connection{Driver: "sqlserver"}}
+	columns := []ddlColumn{
+		{name: "id", typeName: "int IDENTITY(1,1)", notNull: true},
+		{name: "status", typeName: "varchar(10)", defaultValue: sql.NullString{String: "('new')", Valid: true}},
+	}
+	constraints := []constraintInfo{
+		{name: "ck_status", kind: constraintCheck, definition: "CHECK ([status]<>'')"},
+		{name: "fk_parent", kind: constraintForeignKey, columns: []string{"id"}, refTable: "parents",
+			refColumns: []string{"id"}, onDelete: "CASCADE", onUpdate: "NO ACTION"},
+		{name: "pk_items", kind: constraintPrimaryKey, columns: []string{"id"}},
+	}
+
+	expected := `CREATE TABLE [items] (
+    [id] int IDENTITY(1,1) NOT NULL,
+    [status] varchar(10) DEFAULT ('new'),
+    CONSTRAINT [pk_items] PRIMARY KEY ([id]),
+    CONSTRAINT [fk_parent] FOREIGN KEY ([id]) REFERENCES [parents] ([id]) ON DELETE CASCADE,
+    CONSTRAINT [ck_status] CHECK ([status]<>'')
+)`
+	if got := introspector.createTable("items", columns, constraints); got != expected {
+		t.Errorf("Unexpected CREATE TABLE:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestCreateTablePostgres(t *testing.T) {
+	introspector := &Introspector{connection: &database.Connection{Driver: "postgres"}}
+	columns := []ddlColumn{
+		{name: "id", typeName: "bigint", notNull: true, identity: "ALWAYS"},
+		{name: "line", typeName: "integer", notNull: true, serial: true,
+			defaultValue: sql.NullString{String: "nextval('items_line_seq'::regclass)", Valid: true}},
+		{name: "code", typeName: "integer",
+			defaultValue: sql.NullString{String: "nextval('codes'::regclass)", Valid: true}},
+	}
+
+	// Identity and serial columns create their own sequences
+	expected := `CREATE TABLE "items" (
+    "id" bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+    "line" serial NOT NULL,
+    "code" integer DEFAULT nextval('codes'::regclass)
+)`
+	if got := introspector.createTable("items", columns, nil); got != expected {
+		t.Errorf("Unexpected CREATE TABLE:\n%s\nexpected:\n%s", got, expected)
+	}
+}
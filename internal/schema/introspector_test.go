@@ -2,6 +2,7 @@ package schema
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"gosqlpp/internal/database"
@@ -119,3 +120,17 @@ func TestFilterNames(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessDriversColumnOrder(t *testing.T) {
+	var buf bytes.Buffer
+	introspector := NewIntrospector(nil, output.NewFormatter("csv", &buf))
+
+	if err := introspector.ProcessSchemaCommand("@drivers", "sqlite"); err != nil {
+		t.Fatalf("ProcessSchemaCommand(@drivers) returned error: %v", err)
+	}
+
+	expected := "driver_name,type,status,description,connection_example\n"
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("Expected columns in field order, got:\n%s", buf.String())
+	}
+}